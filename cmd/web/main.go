@@ -1,31 +1,23 @@
 package main
 
 import (
-	"flag"
-	"log"
-	"net/http"
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 
-	"readinglist/internal/models"
+	"readinglist/internal/web"
 )
 
-type application struct {
-	readinglist *models.ReadinglistModel
-}
-
+// web runs the web app on its own, the same as readinglist serve-web
 func main() {
-	addr := flag.String("addr", ":80", "HTTP network address")                                                        //using a flag means we can change it with command line arguments
-	endpoint := flag.String("endpoint", "http://localhost:4000/v1/books", "Endpoint for the readinglist web service") //this defines the endpoint for the readinglist
+	//the context is cancelled on SIGINT or SIGTERM, which shuts the server down gracefully
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	app := &application{
-		readinglist: &models.ReadinglistModel{Endpoint: *endpoint}, //this sets the endpoint as a pointer to the endpoint flag that was passed in
+	if err := web.Serve(ctx, "web", os.Args[1:]); err != nil {
+		slog.New(slog.NewJSONHandler(os.Stdout, nil)).Error("web stopped with an error", "error", err)
+		os.Exit(1)
 	}
-
-	srv := &http.Server{
-		Addr:    *addr,
-		Handler: app.routes(),
-	}
-
-	log.Printf("Starting the server on %s", *addr)
-	err := srv.ListenAndServe() //this starts the web application
-	log.Fatal(err)
 }
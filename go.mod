@@ -1,5 +1,13 @@
 module readinglist
 
-go 1.21.4
+go 1.22
 
-require github.com/lib/pq v1.10.9 // indirect
+require github.com/lib/pq v1.10.9
+
+require github.com/mattn/go-sqlite3 v1.14.22
+
+require golang.org/x/crypto v0.31.0
+
+require golang.org/x/time v0.5.0
+
+require gopkg.in/yaml.v3 v3.0.1
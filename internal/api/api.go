@@ -0,0 +1,317 @@
+package api
+
+//this package is the readinglist JSON api
+//it is run by cmd/api and by the serve-api, migrate and all subcommands of the readinglist binary at the root of the module
+
+import (
+	"context"
+	"database/sql" //package provides a generic api that allows for interacting with the databases in a vendor-neutral way
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/lib/pq"           //This is a driver; this is the go package for the sql database driver; third-party package
+	_ "github.com/mattn/go-sqlite3" //the SQLite driver, used when the api is started with -db-driver=sqlite
+
+	"readinglist/internal/data"
+	"readinglist/internal/migrate"
+)
+
+// the build information shown by /v1/healthcheck
+// commit and buildTime are set when the binary is built, for example:
+//
+//	go build -ldflags "-X readinglist/internal/api.commit=$(git rev-parse --short HEAD) -X readinglist/internal/api.buildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)" .
+//
+// version can be set the same way for a release build
+var (
+	version   = "1.0.0"
+	commit    string
+	buildTime string
+)
+
+type application struct {
+	config  config
+	logger  *slog.Logger
+	db      *sql.DB //nil for the memory driver
+	models  data.Models
+	metrics *appMetrics
+	//migrator is used by the readiness check to see whether the schema is current; it is nil for the memory driver
+	migrator *migrate.Migrator
+	//health is the last readiness result, which the health checks share
+	health  readinessCache
+	started time.Time
+	//the background goroutines started with app.background are counted in wg and told to stop when done is closed
+	wg   sync.WaitGroup
+	done chan struct{}
+}
+
+// Serve runs the api until ctx is cancelled; it is what cmd/api and readinglist serve-api do
+// name is the command shown in the usage message and args are the flags
+// the flags can be followed by migrate up|down|status|goto N to change the schema instead of serving, as cmd/api always could,
+// or by permissions grant|revoke|list to change what a user is allowed to do
+func Serve(ctx context.Context, name string, args []string) error {
+	//the settings come from the defaults, a config file, the environment and the flags; see config.go
+	cfg, rest, printCfg, err := loadConfig(name, args, nil)
+	if err != nil {
+		return fmt.Errorf("loading configuration: %w", err)
+	}
+
+	if printCfg {
+		return printConfig(os.Stdout, cfg)
+	}
+
+	logger := newLogger(cfg)
+
+	if len(rest) > 0 && rest[0] == "migrate" {
+		return runMigrate(cfg, logger, rest[1:])
+	}
+
+	if len(rest) > 0 && rest[0] == "permissions" {
+		return runPermissions(ctx, cfg, logger, rest[1:])
+	}
+
+	if len(rest) > 0 {
+		return fmt.Errorf("unexpected arguments %q", rest)
+	}
+
+	srv, err := newServer(cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	return srv.Run(ctx)
+}
+
+// Migrate changes the database schema; args are the flags followed by up, down, status or goto N
+func Migrate(name string, args []string) error {
+	cfg, rest, printCfg, err := loadConfig(name, args, nil)
+	if err != nil {
+		return fmt.Errorf("loading configuration: %w", err)
+	}
+
+	if printCfg {
+		return printConfig(os.Stdout, cfg)
+	}
+
+	return runMigrate(cfg, newLogger(cfg), rest)
+}
+
+// Permissions grants, revokes or lists a user's permissions; args are the flags followed by grant, revoke or list,
+// the user's email address and the permission codes
+func Permissions(name string, args []string) error {
+	cfg, rest, printCfg, err := loadConfig(name, args, nil)
+	if err != nil {
+		return fmt.Errorf("loading configuration: %w", err)
+	}
+
+	if printCfg {
+		return printConfig(os.Stdout, cfg)
+	}
+
+	return runPermissions(context.Background(), cfg, newLogger(cfg), rest)
+}
+
+// Server is the api with its configuration loaded and its database open, ready to Run
+type Server struct {
+	app     *application
+	handler http.Handler
+}
+
+// NewServer loads the configuration from args and opens the database
+// extra registers more settings next to the api's own; readinglist all uses it for the web app's address and token
+// when -print-config is given the configuration is printed and NewServer returns a nil Server and a nil error
+func NewServer(name string, args []string, extra func(fs *flag.FlagSet)) (*Server, error) {
+	cfg, rest, printCfg, err := loadConfig(name, args, extra)
+	if err != nil {
+		return nil, fmt.Errorf("loading configuration: %w", err)
+	}
+
+	if printCfg {
+		return nil, printConfig(os.Stdout, cfg)
+	}
+
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("unexpected arguments %q", rest)
+	}
+
+	return newServer(cfg, newLogger(cfg))
+}
+
+// newLogger writes every log line as a JSON object on stdout so it can be collected and searched
+func newLogger(cfg config) *slog.Logger {
+	//the level was checked by validate, so this can't fail
+	var level slog.Level
+	level.UnmarshalText([]byte(cfg.logLevel))
+
+	return slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level}))
+}
+
+// newServer opens the storage for the configured driver and builds the application around it
+func newServer(cfg config, logger *slog.Logger) (*Server, error) {
+	//the memory driver doesn't need a database, so it is handled before any connection is opened
+	var models data.Models
+	var db *sql.DB //stays nil for the memory driver
+	var migrator *migrate.Migrator
+
+	switch cfg.driver {
+	case "memory":
+		models = data.NewMemoryModels()
+		logger.Warn("using in-memory storage; nothing will be saved when the server stops")
+
+	case "postgres", "sqlite":
+		var err error
+
+		db, err = openDB(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("opening %s database: %w", cfg.driver, err)
+		}
+
+		logger.Info("database connection pool established", "driver", cfg.driver)
+
+		migrator, err = migrate.New(db, cfg.driver)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("loading migrations: %w", err)
+		}
+
+		//a SQLite database is brought up to the latest schema when the server starts because single-user installs have nobody to run migrate up
+		//PostgreSQL databases are left alone; run the migrate subcommand against them instead
+		if cfg.driver == "sqlite" {
+			if err := migrator.Up(); err != nil {
+				db.Close()
+				return nil, fmt.Errorf("migrating database: %w", err)
+			}
+
+			models = data.NewSQLiteModels(db)
+		} else {
+			models = data.NewModels(db)
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown -db-driver %q; expected postgres, sqlite or memory", cfg.driver)
+	}
+
+	app := &application{
+		config:   cfg,
+		logger:   logger,
+		db:       db,
+		models:   models,
+		metrics:  newAppMetrics(db),
+		migrator: migrator,
+		started:  time.Now(),
+		done:     make(chan struct{}),
+	}
+
+	publishExpvars(db)
+
+	return &Server{app: app, handler: app.handler()}, nil
+}
+
+// Run serves the api until ctx is cancelled and then shuts it down gracefully
+// the database is closed last, after every request and background task is done
+func (s *Server) Run(ctx context.Context) error {
+	app := s.app
+
+	err := app.serve(ctx, s.handler)
+
+	if app.db != nil {
+		app.logger.Info("closing database connection pool")
+
+		if closeErr := app.db.Close(); closeErr != nil {
+			app.logger.Error("closing database connection pool", "error", closeErr)
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	app.logger.Info("shutdown complete")
+
+	return nil
+}
+
+// Handler is the api with all of its middleware; readinglist all hands the web app's requests straight to it
+// those requests skip the rate limiter: they all come from one address with the web app's one token,
+// so limiting them would give every visitor to the web app a share of a single bucket
+func (s *Server) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), inProcessContextKey, true)
+		s.handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Logger is the logger the api was configured with, so the web app in readinglist all can log the same way
+func (s *Server) Logger() *slog.Logger {
+	return s.app.logger
+}
+
+// Port is the port the api listens on
+func (s *Server) Port() int {
+	return s.app.config.port
+}
+
+// ShutdownTimeout is the -shutdown-timeout setting
+func (s *Server) ShutdownTimeout() time.Duration {
+	return s.app.config.shutdownTimeout
+}
+
+// ReadTimeout, WriteTimeout and IdleTimeout are the -read-timeout, -write-timeout and -idle-timeout settings
+// readinglist all gives the web app's server the same ones
+func (s *Server) ReadTimeout() time.Duration {
+	return s.app.config.server.readTimeout
+}
+
+func (s *Server) WriteTimeout() time.Duration {
+	return s.app.config.server.writeTimeout
+}
+
+func (s *Server) IdleTimeout() time.Duration {
+	return s.app.config.server.idleTimeout
+}
+
+// openDB opens the connection pool for the chosen driver, sizes it from the config and checks that it works
+// SQLite only allows one writer at a time, so its pool is always limited to one connection
+func openDB(cfg config) (*sql.DB, error) {
+	//the sqlite driver registers itself under the name sqlite3
+	//foreign keys are off by default in SQLite, and the ON DELETE CASCADE rules on book_authors need them
+	driverName, dsn := cfg.driver, cfg.dsn
+	if driverName == "sqlite" {
+		driverName = "sqlite3"
+
+		if !strings.Contains(dsn, "_foreign_keys") {
+			separator := "?"
+			if strings.Contains(dsn, "?") {
+				separator = "&"
+			}
+			dsn += separator + "_foreign_keys=on"
+		}
+	}
+
+	//below opens the database connection
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(cfg.db.maxOpenConns)
+	db.SetMaxIdleConns(cfg.db.maxIdleConns)
+	db.SetConnMaxIdleTime(cfg.db.maxIdleTime)
+
+	if cfg.driver == "sqlite" {
+		db.SetMaxOpenConns(1)
+	}
+
+	err = db.Ping() //this tests the connection
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
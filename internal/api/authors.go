@@ -0,0 +1,238 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"readinglist/internal/data"
+	"readinglist/internal/validator"
+)
+
+//this file holds the handlers for the /v1/authors endpoints
+//they work the same way as the book handlers in handlers.go, and like books each user only sees and changes their own authors
+
+func (app *application) listAuthors(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	v := validator.New()
+
+	name := app.readString(qs, "name", "")
+
+	filters := data.Filters{
+		Page:         app.readInt(qs, "page", 1, v),
+		PageSize:     app.readInt(qs, "page_size", 20, v),
+		Sort:         app.readString(qs, "sort", "name"),
+		SortSafelist: []string{"id", "name", "-id", "-name"},
+	}
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	authors, metadata, err := app.models.Authors.GetAll(r.Context(), user.ID, name, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, envelope{"authors": authors, "metadata": metadata}, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createAuthor(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name string `json:"name"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	author := &data.Author{Name: input.Name, UserID: user.ID}
+
+	v := validator.New()
+
+	if data.ValidateAuthor(v, author); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if err := app.models.Authors.Insert(r.Context(), author); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/authors/%d", author.ID))
+
+	if err := app.writeJSON(w, http.StatusCreated, envelope{"author": author}, headers); err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) getAuthor(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	author, err := app.models.Authors.Get(r.Context(), id, user.ID)
+	if err != nil {
+		app.modelErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, envelope{"author": author}, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateAuthor(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	author, err := app.models.Authors.Get(r.Context(), id, user.ID)
+	if err != nil {
+		app.modelErrorResponse(w, r, err)
+		return
+	}
+
+	var input struct {
+		Name *string `json:"name"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		author.Name = *input.Name
+	}
+
+	v := validator.New()
+
+	if data.ValidateAuthor(v, author); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if err := app.models.Authors.Update(r.Context(), author); err != nil {
+		app.modelErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, envelope{"author": author}, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteAuthor(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	if err := app.models.Authors.Delete(r.Context(), id, user.ID); err != nil {
+		app.modelErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, envelope{"message": "author successfully deleted"}, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listAuthorBooks returns one page of the books an author worked on
+func (app *application) listAuthorBooks(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	//this makes sure a missing author is a 404 rather than an empty list
+	if _, err := app.models.Authors.Get(r.Context(), id, user.ID); err != nil {
+		app.modelErrorResponse(w, r, err)
+		return
+	}
+
+	qs := r.URL.Query()
+	v := validator.New()
+
+	filters := data.Filters{
+		Page:         app.readInt(qs, "page", 1, v),
+		PageSize:     app.readInt(qs, "page_size", 20, v),
+		Sort:         app.readString(qs, "sort", "-published"),
+		SortSafelist: []string{"id", "title", "published", "pages", "rating", "-id", "-title", "-published", "-pages", "-rating"},
+	}
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	//only the books on the user's own list are shown
+	books, metadata, err := app.models.Authors.GetBooks(r.Context(), id, user.ID, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.models.Authors.LoadForBooks(r.Context(), books); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, envelope{"books": books, "metadata": metadata}, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// checkBookAuthors validates the authors sent with a book and makes sure each one exists and belongs to userID
+// problems are added to v so they come back in the same 422 response as the other book fields
+func (app *application) checkBookAuthors(ctx context.Context, v *validator.Validator, userID int64, authors []data.BookAuthor) error {
+	data.ValidateBookAuthors(v, authors)
+
+	for _, author := range authors {
+		_, err := app.models.Authors.Get(ctx, author.ID, userID)
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("authors", fmt.Sprintf("author %d does not exist", author.ID))
+		case err != nil:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// saveBookAuthors links the authors to the book and then loads them back so the response includes their names
+func (app *application) saveBookAuthors(ctx context.Context, book *data.Book, authors []data.BookAuthor) error {
+	if err := app.models.Authors.SetForBook(ctx, book.ID, authors); err != nil {
+		return err
+	}
+
+	return app.models.Authors.LoadForBooks(ctx, []*data.Book{book})
+}
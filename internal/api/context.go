@@ -0,0 +1,34 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"readinglist/internal/data"
+)
+
+// contextKey is a private type for the keys this package puts in a request context
+// using our own type means the keys can't clash with ones set by other packages
+type contextKey string
+
+const userContextKey = contextKey("user")
+
+// inProcessContextKey marks the requests readinglist all's web app hands straight to the api, see Server.Handler
+const inProcessContextKey = contextKey("in-process")
+
+// contextSetUser returns a copy of the request with the user added to its context
+func (app *application) contextSetUser(r *http.Request, user *data.User) *http.Request {
+	ctx := context.WithValue(r.Context(), userContextKey, user)
+	return r.WithContext(ctx)
+}
+
+// contextGetUser gets the user that authenticate added to the request
+// it is only called after authenticate has run, so a missing user is a bug and panics
+func (app *application) contextGetUser(r *http.Request) *data.User {
+	user, ok := r.Context().Value(userContextKey).(*data.User)
+	if !ok {
+		panic("missing user value in request context")
+	}
+
+	return user
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"readinglist/internal/data"
+	"readinglist/internal/requestid"
+)
+
+//this file holds the helpers every handler uses to send an error back to the client
+//all of the errors are sent in the same JSON envelope, for example {"error": "the requested resource could not be found"}
+//so the client never has to deal with a plain-text body
+//the request id goes in the body as well so a client reporting an error can quote it
+
+// errorResponse is the helper all of the others below use
+// message is an any so it can be a single string or something bigger like a map of field errors
+func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, status int, message any) {
+	env := envelope{"error": message, "request_id": requestid.FromContext(r.Context())}
+
+	err := app.writeJSON(w, status, env, nil)
+	if err != nil {
+		//if the JSON can't be written there is nothing more to send, so the error is only logged
+		app.logger.Error("writing error response", "method", r.Method, "uri", r.URL.RequestURI(), "request_id", requestid.FromContext(r.Context()), "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// statusClientClosedRequest is the status nginx uses for a request the client gave up on; net/http has no name for it
+const statusClientClosedRequest = 499
+
+// serverErrorResponse logs the real error and sends a generic message so internal details don't leak to the client
+// when the client has already gone the error is most likely the cancelled query, so it is treated as clientClosedResponse instead
+func (app *application) serverErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	if r.Context().Err() != nil {
+		app.clientClosedResponse(w, r, err)
+		return
+	}
+
+	app.logger.Error("server error", "method", r.Method, "uri", r.URL.RequestURI(), "request_id", requestid.FromContext(r.Context()), "error", err)
+
+	message := "the server encountered a problem and could not process your request"
+	app.errorResponse(w, r, http.StatusInternalServerError, message)
+}
+
+func (app *application) notFoundResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the requested resource could not be found"
+	app.errorResponse(w, r, http.StatusNotFound, message)
+}
+
+func (app *application) methodNotAllowedResponse(w http.ResponseWriter, r *http.Request) {
+	message := fmt.Sprintf("the %s method is not supported for this resource", r.Method)
+	app.errorResponse(w, r, http.StatusMethodNotAllowed, message)
+}
+
+func (app *application) badRequestResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+}
+
+// failedValidationResponse sends the map of field names to messages from a validator with a 422 status
+func (app *application) failedValidationResponse(w http.ResponseWriter, r *http.Request, errors map[string]string) {
+	app.errorResponse(w, r, http.StatusUnprocessableEntity, errors)
+}
+
+func (app *application) editConflictResponse(w http.ResponseWriter, r *http.Request) {
+	message := "unable to modify the record due to an edit conflict, please try again"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+func (app *application) duplicateResponse(w http.ResponseWriter, r *http.Request) {
+	message := "a record with these details already exists"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+func (app *application) preconditionFailedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the record has changed since it was read; fetch it again to get the current ETag"
+	app.errorResponse(w, r, http.StatusPreconditionFailed, message)
+}
+
+// unsupportedPatchTypeResponse lists the patch formats that can be used in the Accept-Patch header, as RFC 5789 asks
+func (app *application) unsupportedPatchTypeResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Accept-Patch", mergePatchType+", "+jsonPatchType)
+
+	message := fmt.Sprintf("the Content-Type must be %s or %s", mergePatchType, jsonPatchType)
+	app.errorResponse(w, r, http.StatusUnsupportedMediaType, message)
+}
+
+func (app *application) invalidCredentialsResponse(w http.ResponseWriter, r *http.Request) {
+	message := "invalid authentication credentials"
+	app.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+// invalidAuthenticationTokenResponse tells the client how it is supposed to authenticate with the WWW-Authenticate header
+func (app *application) invalidAuthenticationTokenResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+
+	message := "invalid or missing authentication token"
+	app.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+func (app *application) authenticationRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+
+	message := "you must be authenticated to access this resource"
+	app.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "your user account doesn't have the necessary permissions to access this resource"
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+// rateLimitExceededResponse tells the client how many seconds to wait, both in the Retry-After header and in the body
+func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request, retryAfter int) {
+	headers := make(http.Header)
+	headers.Set("Retry-After", strconv.Itoa(retryAfter))
+
+	env := envelope{"error": "rate limit exceeded", "retry_after": retryAfter, "request_id": requestid.FromContext(r.Context())}
+
+	if err := app.writeJSON(w, http.StatusTooManyRequests, env, headers); err != nil {
+		app.logger.Error("writing error response", "method", r.Method, "uri", r.URL.RequestURI(), "request_id", requestid.FromContext(r.Context()), "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// clientClosedResponse is for a request whose client disconnected, which cancels the request context and with it the query
+// nobody is left to read a body, but the 499 is still written so the request log and the metrics don't count it as a 200
+func (app *application) clientClosedResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Info("request cancelled by the client", "method", r.Method, "uri", r.URL.RequestURI(), "request_id", requestid.FromContext(r.Context()), "error", err)
+	w.WriteHeader(statusClientClosedRequest)
+}
+
+// modelErrorResponse is the one place where errors coming back from internal/data are turned into status codes
+// handlers call it with any error from a model and it picks the right response
+func (app *application) modelErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case r.Context().Err() != nil:
+		app.clientClosedResponse(w, r, err)
+	case errors.Is(err, data.ErrRecordNotFound):
+		app.notFoundResponse(w, r)
+	case errors.Is(err, data.ErrEditConflict):
+		app.editConflictResponse(w, r)
+	case errors.Is(err, data.ErrDuplicate):
+		app.duplicateResponse(w, r)
+	default:
+		app.serverErrorResponse(w, r, err)
+	}
+}
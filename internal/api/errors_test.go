@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"readinglist/internal/data"
+)
+
+func TestModelErrorResponse(t *testing.T) {
+	app := newTestApplication(t, config{})
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		err        error
+		wantStatus int
+		wantBody   bool
+	}{
+		{name: "not found", ctx: context.Background(), err: data.ErrRecordNotFound, wantStatus: http.StatusNotFound, wantBody: true},
+		{name: "wrapped not found", ctx: context.Background(), err: fmt.Errorf("getting book: %w", data.ErrRecordNotFound), wantStatus: http.StatusNotFound, wantBody: true},
+		{name: "edit conflict", ctx: context.Background(), err: data.ErrEditConflict, wantStatus: http.StatusConflict, wantBody: true},
+		{name: "duplicate", ctx: context.Background(), err: data.ErrDuplicate, wantStatus: http.StatusConflict, wantBody: true},
+		{name: "anything else", ctx: context.Background(), err: errors.New("connection refused"), wantStatus: http.StatusInternalServerError, wantBody: true},
+		//the client is gone, so nothing is sent but the status, which is what the request log and the metrics see
+		{name: "client gone", ctx: cancelled, err: context.Canceled, wantStatus: statusClientClosedRequest},
+		{name: "client gone with another error", ctx: cancelled, err: errors.New("pq: canceling statement due to user request"), wantStatus: statusClientClosedRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/v1/books/1", nil).WithContext(tt.ctx)
+
+			app.modelErrorResponse(rr, r, tt.err)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("status = %d; want %d", rr.Code, tt.wantStatus)
+			}
+
+			if gotBody := rr.Body.Len() > 0; gotBody != tt.wantBody {
+				t.Errorf("body = %q; want a body: %t", rr.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestServerErrorResponseClientGone(t *testing.T) {
+	app := newTestApplication(t, config{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	rr := httptest.NewRecorder()
+	app.serverErrorResponse(rr, httptest.NewRequest(http.MethodGet, "/v1/authors/1/books", nil).WithContext(ctx), context.Canceled)
+
+	if rr.Code != statusClientClosedRequest {
+		t.Errorf("status = %d; want %d", rr.Code, statusClientClosedRequest)
+	}
+}
@@ -0,0 +1,485 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"readinglist/internal/data" // this imports the data package; one can use the cat go.mod command in terminal to determine how to begin import statement if needed
+	"readinglist/internal/jsonpatch"
+	"readinglist/internal/validator"
+)
+
+// listBooks returns one page of the user's books; it is used for GET /v1/books
+func (app *application) listBooks(w http.ResponseWriter, r *http.Request) {
+	//the input struct holds the filters that can be passed in on the query string
+	//for example /v1/books?title=dune&genres=fiction,classic&page=2&page_size=10&sort=-rating
+	//or /v1/books?q=lord+rings for a full-text search that ignores title, genres and sort
+	var input struct {
+		Title  string
+		Genres []string
+		data.Filters
+	}
+
+	qs := r.URL.Query()
+
+	input.Title = app.readString(qs, "title", "")
+	input.Genres = app.readCSV(qs, "genres", []string{})
+
+	v := validator.New()
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+
+	input.Filters.Sort = app.readString(qs, "sort", "id")
+	//these are the only values the client can sort by; the "-" versions sort in descending order
+	input.Filters.SortSafelist = []string{"id", "title", "published", "pages", "rating", "-id", "-title", "-published", "-pages", "-rating"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	var err error
+
+	//each user only sees the books on their own list
+	user := app.contextGetUser(r)
+
+	//The variable book defines a slice of the data type called Book
+	//when there is a q parameter the books are found with a full-text search and ordered by relevance instead
+	var books []*data.Book
+	var metadata data.Metadata
+
+	if q := app.readString(qs, "q", ""); q != "" {
+		books, metadata, err = app.models.Books.Search(r.Context(), user.ID, q, input.Filters)
+	} else {
+		books, metadata, err = app.models.Books.GetAll(r.Context(), user.ID, input.Title, input.Genres, input.Filters)
+	}
+	if err != nil {
+		app.modelErrorResponse(w, r, err)
+		return
+	}
+
+	//the authors live in their own table so they are added to the books here
+	if err := app.models.Authors.LoadForBooks(r.Context(), books); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	//The code below calls the helper.go function to format, marshall, and write the json
+	//the envelope that is wrapping the books variable is naming that collection of data books and then returning the data of the books variable
+	//the metadata sits next to the books so the client knows which page it is on and how many pages there are
+	if err := app.writeJSON(w, http.StatusOK, envelope{"books": books, "metadata": metadata}, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// createBook adds a book to the user's list; it is used for POST /v1/books
+func (app *application) createBook(w http.ResponseWriter, r *http.Request) {
+	// fmt.Fprintln(w, "Added a new book to the reading list")
+	//below are the pieces of information we expect that will then be unmarshalled into a go object
+	//we are not using the Book struct that already exists because that contains different fields we don't need/want
+	var input struct {
+		Title     string   `json:"title"`
+		Published int      `json:"published"`
+		Pages     int      `json:"pages"`
+		Genres    []string `json:"genres"`
+		Rating    float32  `json:"rating"`
+		//the reading status defaults to want_to_read when it isn't sent
+		Status      string `json:"status"`
+		CurrentPage int    `json:"current_page"`
+		//each author is sent as {"id": 1, "role": "author"}
+		Authors []data.BookAuthor `json:"authors"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// fmt.Fprintf(w, "%v\n", input) //this prints out the http response formatted with line breaks as the input struct
+
+	book := &data.Book{
+		Title:     input.Title,
+		Published: input.Published,
+		Pages:     input.Pages,
+		Genres:    input.Genres,
+		Rating:    input.Rating,
+		//every book starts out as want_to_read and SetStatus moves it on so the start and finish dates get filled in
+		Status:      data.StatusWantToRead,
+		CurrentPage: input.CurrentPage,
+		//the new book goes on the list of the user who sent the request
+		UserID: app.contextGetUser(r).ID,
+	}
+
+	//the book is checked before it goes anywhere near the database
+	v := validator.New()
+
+	if input.Status != "" {
+		app.setBookStatus(v, book, input.Status)
+	}
+
+	data.ValidateBook(v, book)
+	if err := app.checkBookAuthors(r.Context(), v, book.UserID, input.Authors); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Books.Insert(r.Context(), book)
+	if err != nil {
+		app.modelErrorResponse(w, r, err)
+		return
+	}
+
+	//the links to the authors can only be saved once the book has an id
+	if err := app.saveBookAuthors(r.Context(), book, input.Authors); err != nil {
+		app.modelErrorResponse(w, r, err)
+		return
+	}
+
+	//this makes the application aware of the new location for the new book
+	headers := make(http.Header)                                 //this makes the new header for the http response
+	headers.Set("Location", fmt.Sprintf("v1/books/%d", book.ID)) //this sets the location of the book to the value of the the books/ api with the new book's id appended to it
+	headers.Set("ETag", etag(book.Version))                      //this is the version the client sends back in If-Match when it changes the book
+
+	//This writes the JSON response with a 201 Created status code and the Location header set
+	err = app.writeJSON(w, http.StatusCreated, envelope{"book": book}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// each of the methods below get the id of the book in question from the {id} in the route pattern with readIDParam
+// getting a specific book
+func (app *application) getBook(w http.ResponseWriter, r *http.Request) {
+	//below is where we get access the book id from the url
+	idInt, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	//this will be removed when this application si connection to a database
+	//this is using the struct from the internal/data package
+	//someone else's book is reported as not found so the ids of other users' books aren't given away
+	book, err := app.models.Books.Get(r.Context(), idInt, app.contextGetUser(r).ID)
+	if err != nil {
+		app.modelErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.models.Authors.LoadForBooks(r.Context(), []*data.Book{book}); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	//the version is sent as the ETag so the client can send it back in If-Match when it changes the book
+	headers := make(http.Header)
+	headers.Set("ETag", etag(book.Version))
+	headers.Set("Accept-Patch", mergePatchType+", "+jsonPatchType)
+
+	//The code below calls the helper.go function to format, marshall, and write the json
+	//the envelope that is wrapping the book variable is naming that collection of data book and then returning the data of the book variable
+	if err := app.writeJSON(w, http.StatusOK, envelope{"book": book}, headers); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+}
+
+// bookInput is the part of a book a client can change
+// PUT sends all of it and PATCH changes the JSON encoding of it, so both end up in replaceBook
+// every field is a pointer so a missing field can be told apart from a zero value
+type bookInput struct {
+	Title     *string   `json:"title"`
+	Published *int      `json:"published"`
+	Pages     *int      `json:"pages"`
+	Genres    *[]string `json:"genres"`
+	Rating    *float32  `json:"rating"`
+	//changing the status goes through book.SetStatus so only the allowed transitions get through
+	Status      *string            `json:"status"`
+	CurrentPage *int               `json:"current_page"`
+	Authors     *[]data.BookAuthor `json:"authors"`
+}
+
+// newBookInput is the book as a bookInput; it is the document a PATCH is applied to
+func newBookInput(book *data.Book) bookInput {
+	authors := book.Authors
+	if authors == nil {
+		authors = []data.BookAuthor{}
+	}
+
+	return bookInput{
+		Title:       &book.Title,
+		Published:   &book.Published,
+		Pages:       &book.Pages,
+		Genres:      &book.Genres,
+		Rating:      &book.Rating,
+		Status:      &book.Status,
+		CurrentPage: &book.CurrentPage,
+		Authors:     &authors,
+	}
+}
+
+// the media types PATCH accepts; they are sent in the Accept-Patch header
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+// updateBook replaces the whole book with the one in the request body, so every field has to be sent
+func (app *application) updateBook(w http.ResponseWriter, r *http.Request) {
+	book, ok := app.bookForUpdate(w, r)
+	if !ok {
+		return
+	}
+
+	//the book from a GET can be sent back as it is, so the members a client can't change are accepted and ignored
+	var input struct {
+		bookInput
+		ID         json.RawMessage `json:"id"`
+		StartedAt  json.RawMessage `json:"started_at"`
+		FinishedAt json.RawMessage `json:"finished_at"`
+	}
+
+	//uses the helper function to unmarshall the json into a go object
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	app.replaceBook(w, r, book, input.bookInput)
+}
+
+// patchBook changes part of a book
+// a merge patch looks like the book with only the changed fields, and null removes a field
+// a JSON patch is a list of operations, which can also add, remove and replace single genres or authors, such as
+// [{"op": "remove", "path": "/genres/1"}, {"op": "add", "path": "/genres/-", "value": "Fantasy"}]
+// the patched book has to be a complete, valid book, the same as the body of a PUT
+func (app *application) patchBook(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != mergePatchType && mediaType != jsonPatchType {
+		app.unsupportedPatchTypeResponse(w, r)
+		return
+	}
+
+	book, ok := app.bookForUpdate(w, r)
+	if !ok {
+		return
+	}
+
+	//the patch is applied to the book as the client sees it, authors included
+	if err := app.models.Authors.LoadForBooks(r.Context(), []*data.Book{book}); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	doc, err := json.Marshal(newBookInput(book))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	patch, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1_048_576))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var patched []byte
+	if mediaType == mergePatchType {
+		patched, err = jsonpatch.Merge(doc, patch)
+	} else {
+		patched, err = jsonpatch.Apply(doc, patch)
+	}
+
+	switch {
+	case errors.Is(err, jsonpatch.ErrTestFailed):
+		app.errorResponse(w, r, http.StatusConflict, err.Error())
+		return
+	case errors.Is(err, jsonpatch.ErrCannotApply):
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, err.Error())
+		return
+	case err != nil:
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	//the patch can add members the book doesn't have or give a field the wrong type, so the result is decoded as strictly as a request body
+	var input bookInput
+
+	dec := json.NewDecoder(bytes.NewReader(patched))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&input); err != nil {
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, fmt.Sprintf("the patched book is not valid: %v", err))
+		return
+	}
+
+	app.replaceBook(w, r, book, input)
+}
+
+// bookForUpdate reads the book in the url for PUT and PATCH and checks the If-Match header against it
+// when it returns false a response has already been sent
+func (app *application) bookForUpdate(w http.ResponseWriter, r *http.Request) (*data.Book, bool) {
+	//below is where we get access the book id from the url
+	idInt, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	book, err := app.models.Books.Get(r.Context(), idInt, app.contextGetUser(r).ID) //this calls the database to get the specific book record with the id from the url
+	if err != nil {
+		app.modelErrorResponse(w, r, err)
+		return nil, false
+	}
+
+	//if the client sent If-Match it has to match the version in the database
+	//otherwise someone else changed the book after the client read it and this update would overwrite their changes
+	condition, err := app.readIfMatch(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return nil, false
+	}
+
+	if !condition.matches(book.Version) {
+		app.preconditionFailedResponse(w, r)
+		return nil, false
+	}
+
+	return book, true
+}
+
+// replaceBook sets every field of the book from input, validates it and saves it
+// a missing field is a validation error rather than being left as it was
+func (app *application) replaceBook(w http.ResponseWriter, r *http.Request, book *data.Book, input bookInput) {
+	v := validator.New()
+
+	v.Check(input.Title != nil, "title", "must be provided")
+	v.Check(input.Published != nil, "published", "must be provided")
+	v.Check(input.Pages != nil, "pages", "must be provided")
+	v.Check(input.Genres != nil, "genres", "must be provided")
+	v.Check(input.Rating != nil, "rating", "must be provided")
+	v.Check(input.Status != nil, "status", "must be provided")
+	v.Check(input.CurrentPage != nil, "current_page", "must be provided")
+	v.Check(input.Authors != nil, "authors", "must be provided")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	book.Title = *input.Title
+	book.Published = *input.Published
+	book.Pages = *input.Pages
+	book.Genres = *input.Genres
+	book.Rating = *input.Rating
+
+	//the status goes first because a move can put the current page somewhere new, for example re-reading a finished book starts at 0
+	//the client's current_page is only applied when they changed it, so sending back the page they read along with a new status keeps the move
+	previousPage := book.CurrentPage
+	app.setBookStatus(v, book, *input.Status)
+
+	if *input.CurrentPage != previousPage {
+		switch book.Status {
+		case data.StatusFinished:
+			v.Check(*input.CurrentPage == book.Pages, "current_page", "must be the last page for a finished book")
+		case data.StatusWantToRead:
+			v.Check(*input.CurrentPage == 0, "current_page", "must be 0 for a book that hasn't been started")
+		}
+		book.CurrentPage = *input.CurrentPage
+	}
+
+	//the book is validated after the changes are applied so the whole record is checked
+	data.ValidateBook(v, book)
+	if err := app.checkBookAuthors(r.Context(), v, book.UserID, *input.Authors); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	//this is where the record is being updated in the database
+	err := app.models.Books.Update(r.Context(), book)
+	if err != nil {
+		app.modelErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.saveBookAuthors(r.Context(), book, *input.Authors); err != nil {
+		app.modelErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", etag(book.Version))
+
+	//this returns back a response of what was updated
+	if err := app.writeJSON(w, http.StatusOK, envelope{"book": book}, headers); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) deleteBook(w http.ResponseWriter, r *http.Request) {
+	//below is where we get access the book id from the url
+	idInt, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	//like updateBook, an If-Match header has to match the version of the book that is being deleted
+	condition, err := app.readIfMatch(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	//0 deletes whatever version is stored, otherwise it is the version that passed the If-Match check
+	var expectedVersion int32
+	if condition.present && !condition.any {
+		book, err := app.models.Books.Get(r.Context(), idInt, user.ID)
+		if err != nil {
+			app.modelErrorResponse(w, r, err)
+			return
+		}
+
+		if !condition.matches(book.Version) {
+			app.preconditionFailedResponse(w, r)
+			return
+		}
+		expectedVersion = book.Version
+	}
+
+	//passing the version makes the delete fail if the book changes between the check above and the delete
+	err = app.models.Books.Delete(r.Context(), idInt, user.ID, expectedVersion)
+	if err != nil {
+		app.modelErrorResponse(w, r, err)
+		return
+	}
+
+	//this is a returned response that uses the app.WriteJSON helper function that says the book was deleted
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "book successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
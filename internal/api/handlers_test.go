@@ -0,0 +1,561 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"readinglist/internal/data"
+)
+
+// newBooksApplication is an api on the memory driver with one user who has books:read and books:write
+// it returns the whole handler chain and the user's token
+func newBooksApplication(t *testing.T) (http.Handler, string) {
+	t.Helper()
+
+	app, token := newBooksApp(t)
+	return app.handler(), token
+}
+
+// newBooksApp is newBooksApplication for tests that need to reach into the application, for example to swap a store
+func newBooksApp(t *testing.T) (*application, string) {
+	t.Helper()
+
+	app := &application{
+		logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		models:  data.NewMemoryModels(),
+		metrics: newAppMetrics(nil),
+		started: time.Now(),
+		done:    make(chan struct{}),
+	}
+
+	ctx := context.Background()
+
+	user := &data.User{Name: "Alice", Email: "alice@example.com"}
+	if err := user.Password.Set("pa55word123"); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.models.Users.Insert(ctx, user, data.DefaultPermissions...); err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := data.GenerateToken(user.ID, time.Hour, data.ScopeAuthentication)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := app.models.Tokens.Insert(ctx, token); err != nil {
+		t.Fatal(err)
+	}
+
+	return app, token.Plaintext
+}
+
+// send makes an authenticated request and decodes the JSON response
+func send(t *testing.T, handler http.Handler, token, method, path, contentType, body string) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	return sendHeader(t, handler, token, method, path, header, body)
+}
+
+// sendHeader is send with any request headers, such as If-Match
+func sendHeader(t *testing.T, handler http.Handler, token, method, path string, header http.Header, body string) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header = header.Clone()
+	r.Header.Set("Authorization", "Bearer "+token)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, r)
+
+	var resp map[string]any
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("%s %s: body %q isn't JSON: %v", method, path, rr.Body.String(), err)
+	}
+
+	return rr, resp
+}
+
+func TestUpdateAndPatchBook(t *testing.T) {
+	const book = `{"title": "The Hobbit", "published": 1937, "pages": 310, "genres": ["Fantasy", "Adventure", "Classic"],
+		"rating": 4.5, "status": "want_to_read", "current_page": 0, "authors": []}`
+
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		wantStatus  int
+		wantGenres  []any //checked when the update works
+		wantErrors  []string
+	}{
+		{
+			name:       "put missing field",
+			method:     http.MethodPut,
+			body:       `{"title": "The Hobbit", "published": 1937, "pages": 310, "genres": [], "rating": 4.5, "status": "want_to_read", "current_page": 0}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantErrors: []string{"authors"},
+		},
+		{
+			//an array in a merge patch replaces the whole array, and a book needs at least one genre
+			name:        "merge patch empties genres",
+			method:      http.MethodPatch,
+			contentType: "application/merge-patch+json",
+			body:        `{"genres": []}`,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantErrors:  []string{"genres"},
+		},
+		{
+			name:        "merge patch changes one field",
+			method:      http.MethodPatch,
+			contentType: "application/merge-patch+json",
+			body:        `{"genres": ["Fantasy"]}`,
+			wantStatus:  http.StatusOK,
+			wantGenres:  []any{"Fantasy"},
+		},
+		{
+			name:        "merge patch removing a required field",
+			method:      http.MethodPatch,
+			contentType: "application/merge-patch+json",
+			body:        `{"title": null}`,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantErrors:  []string{"title"},
+		},
+		{
+			name:        "json patch removes one genre",
+			method:      http.MethodPatch,
+			contentType: "application/json-patch+json",
+			body:        `[{"op": "remove", "path": "/genres/1"}]`,
+			wantStatus:  http.StatusOK,
+			wantGenres:  []any{"Fantasy", "Classic"},
+		},
+		{
+			name:        "json patch with a member it doesn't use",
+			method:      http.MethodPatch,
+			contentType: "application/json-patch+json",
+			body:        `[{"op": "remove", "path": "/genres/1", "value": "ignored"}]`,
+			wantStatus:  http.StatusOK,
+			wantGenres:  []any{"Fantasy", "Classic"},
+		},
+		{
+			name:        "json patch test fails",
+			method:      http.MethodPatch,
+			contentType: "application/json-patch+json",
+			body:        `[{"op": "test", "path": "/title", "value": "The Silmarillion"}, {"op": "replace", "path": "/title", "value": "Unfinished Tales"}]`,
+			wantStatus:  http.StatusConflict,
+		},
+		{
+			name:        "json patch path that doesn't exist",
+			method:      http.MethodPatch,
+			contentType: "application/json-patch+json",
+			body:        `[{"op": "remove", "path": "/genres/9"}]`,
+			wantStatus:  http.StatusUnprocessableEntity,
+		},
+		{
+			name:        "json patch adding an unknown field",
+			method:      http.MethodPatch,
+			contentType: "application/json-patch+json",
+			body:        `[{"op": "add", "path": "/isbn", "value": "9780261103344"}]`,
+			wantStatus:  http.StatusUnprocessableEntity,
+		},
+		{
+			name:        "wrong content type",
+			method:      http.MethodPatch,
+			contentType: "application/json",
+			body:        `{"genres": []}`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, token := newBooksApplication(t)
+
+			rr, resp := send(t, handler, token, http.MethodPost, "/v1/books", "application/json", book)
+			if rr.Code != http.StatusCreated {
+				t.Fatalf("creating the book: %d %v", rr.Code, resp)
+			}
+
+			rr, resp = send(t, handler, token, tt.method, "/v1/books/1", tt.contentType, tt.body)
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("status = %d %v; want %d", rr.Code, resp, tt.wantStatus)
+			}
+
+			if tt.wantStatus == http.StatusUnsupportedMediaType {
+				if got := rr.Header().Get("Accept-Patch"); got != "application/merge-patch+json, application/json-patch+json" {
+					t.Errorf("Accept-Patch = %q; want both patch types", got)
+				}
+			}
+
+			for _, field := range tt.wantErrors {
+				if errs, _ := resp["error"].(map[string]any); errs[field] == nil {
+					t.Errorf("error = %v; want a message for %s", resp["error"], field)
+				}
+			}
+
+			if tt.wantStatus != http.StatusOK {
+				//a failed update leaves the book as it was
+				_, resp = send(t, handler, token, http.MethodGet, "/v1/books/1", "", "")
+				if got := resp["book"].(map[string]any)["title"]; got != "The Hobbit" {
+					t.Errorf("title after a failed update = %v; want it unchanged", got)
+				}
+				return
+			}
+
+			got := resp["book"].(map[string]any)["genres"]
+			if !equalAny(got, tt.wantGenres) {
+				t.Errorf("genres = %v; want %v", got, tt.wantGenres)
+			}
+		})
+	}
+}
+
+// equalAny compares two decoded JSON values by their encoding
+func equalAny(a, b any) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	return string(x) == string(y)
+}
+
+func TestInvalidIDNotFound(t *testing.T) {
+	handler, token := newBooksApplication(t)
+
+	//an id that can't exist is a missing record, the same on every route with an {id}
+	for _, req := range []struct{ method, path string }{
+		{http.MethodGet, "/v1/books/abc"},
+		{http.MethodPut, "/v1/books/0"},
+		{http.MethodPatch, "/v1/books/-1"},
+		{http.MethodDelete, "/v1/books/abc"},
+		{http.MethodGet, "/v1/books/abc/progress"},
+		{http.MethodPost, "/v1/books/abc/progress"},
+		{http.MethodGet, "/v1/authors/abc"},
+		{http.MethodPut, "/v1/authors/0"},
+		{http.MethodDelete, "/v1/authors/abc"},
+		{http.MethodGet, "/v1/authors/abc/books"},
+	} {
+		contentType := "application/json"
+		if req.method == http.MethodPatch {
+			contentType = "application/merge-patch+json"
+		}
+
+		if rr, resp := send(t, handler, token, req.method, req.path, contentType, "{}"); rr.Code != http.StatusNotFound {
+			t.Errorf("%s %s = %d %v; want %d", req.method, req.path, rr.Code, resp, http.StatusNotFound)
+		}
+	}
+}
+
+// racingBooks is a BookStore where another client always saves the book just before Update does
+// so the version the handler checked is stale by the time it writes
+type racingBooks struct {
+	data.BookStore
+}
+
+func (b racingBooks) Update(ctx context.Context, book *data.Book) error {
+	other := *book
+	if err := b.BookStore.Update(ctx, &other); err != nil {
+		return err
+	}
+	return b.BookStore.Update(ctx, book)
+}
+
+func TestIfMatch(t *testing.T) {
+	const book = `{"title": "The Hobbit", "published": 1937, "pages": 310, "genres": ["Fantasy"],
+		"rating": 4.5, "status": "want_to_read", "current_page": 0, "authors": []}`
+	const patch = `{"pages": 320}`
+
+	tests := []struct {
+		name       string
+		method     string
+		ifMatch    string
+		racing     bool
+		wantStatus int
+	}{
+		{name: "no header", method: http.MethodPatch, wantStatus: http.StatusOK},
+		{name: "current version", method: http.MethodPatch, ifMatch: `"1"`, wantStatus: http.StatusOK},
+		{name: "any version", method: http.MethodPatch, ifMatch: `*`, wantStatus: http.StatusOK},
+		{name: "list with the current version", method: http.MethodPatch, ifMatch: `"7", "1"`, wantStatus: http.StatusOK},
+		{name: "stale version", method: http.MethodPatch, ifMatch: `"2"`, wantStatus: http.StatusPreconditionFailed},
+		{name: "list without the current version", method: http.MethodPatch, ifMatch: `"2", "3"`, wantStatus: http.StatusPreconditionFailed},
+		{name: "weak tag never matches", method: http.MethodPatch, ifMatch: `W/"1"`, wantStatus: http.StatusPreconditionFailed},
+		{name: "tag that isn't a version", method: http.MethodPatch, ifMatch: `"abc"`, wantStatus: http.StatusPreconditionFailed},
+		{name: "unquoted", method: http.MethodPatch, ifMatch: `1`, wantStatus: http.StatusBadRequest},
+		{name: "trailing comma", method: http.MethodPatch, ifMatch: `"1",`, wantStatus: http.StatusBadRequest},
+		{name: "changed between the check and the save", method: http.MethodPatch, ifMatch: `"1"`, racing: true, wantStatus: http.StatusConflict},
+		{name: "delete current version", method: http.MethodDelete, ifMatch: `"1"`, wantStatus: http.StatusOK},
+		{name: "delete list with the current version", method: http.MethodDelete, ifMatch: `W/"1", "1"`, wantStatus: http.StatusOK},
+		{name: "delete any version", method: http.MethodDelete, ifMatch: `*`, wantStatus: http.StatusOK},
+		{name: "delete stale version", method: http.MethodDelete, ifMatch: `"2"`, wantStatus: http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, token := newBooksApp(t)
+			handler := app.handler()
+
+			rr, resp := send(t, handler, token, http.MethodPost, "/v1/books", "application/json", book)
+			if rr.Code != http.StatusCreated {
+				t.Fatalf("creating the book: %d %v", rr.Code, resp)
+			}
+
+			if tt.racing {
+				app.models.Books = racingBooks{app.models.Books}
+			}
+
+			header := http.Header{}
+			if tt.method == http.MethodPatch {
+				header.Set("Content-Type", "application/merge-patch+json")
+			}
+			if tt.ifMatch != "" {
+				header.Set("If-Match", tt.ifMatch)
+			}
+
+			rr, resp = sendHeader(t, handler, token, tt.method, "/v1/books/1", header, patch)
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("status = %d %v; want %d", rr.Code, resp, tt.wantStatus)
+			}
+
+			if tt.wantStatus == http.StatusPreconditionFailed {
+				//a failed precondition leaves the book as it was
+				rr, resp = send(t, handler, token, http.MethodGet, "/v1/books/1", "", "")
+				if rr.Code != http.StatusOK || resp["book"].(map[string]any)["pages"] != float64(310) {
+					t.Errorf("book after a failed precondition = %d %v; want it unchanged", rr.Code, resp)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateStatusAndCurrentPage(t *testing.T) {
+	const book = `{"title": "The Hobbit", "published": 1937, "pages": 310, "genres": ["Fantasy"],
+		"rating": 4.5, "status": "%s", "current_page": %d, "authors": []}`
+
+	tests := []struct {
+		name        string
+		from        string
+		page        int
+		method      string
+		contentType string
+		body        string
+		wantStatus  int
+		wantPage    float64
+		wantErrors  []string
+	}{
+		{
+			name:       "re-reading a finished book from a page",
+			from:       "finished",
+			page:       310,
+			method:     http.MethodPut,
+			body:       fmt.Sprintf(book, "reading", 50),
+			wantStatus: http.StatusOK,
+			wantPage:   50,
+		},
+		{
+			name:        "merge patch re-reading a finished book from a page",
+			from:        "finished",
+			page:        310,
+			method:      http.MethodPatch,
+			contentType: "application/merge-patch+json",
+			body:        `{"status": "reading", "current_page": 50}`,
+			wantStatus:  http.StatusOK,
+			wantPage:    50,
+		},
+		{
+			//the page isn't sent, so the move decides it
+			name:        "merge patch re-reading a finished book",
+			from:        "finished",
+			page:        310,
+			method:      http.MethodPatch,
+			contentType: "application/merge-patch+json",
+			body:        `{"status": "reading"}`,
+			wantStatus:  http.StatusOK,
+			wantPage:    0,
+		},
+		{
+			//the client sent back the page it read along with the new status
+			name:       "finishing a book",
+			from:       "reading",
+			page:       120,
+			method:     http.MethodPut,
+			body:       fmt.Sprintf(book, "finished", 120),
+			wantStatus: http.StatusOK,
+			wantPage:   310,
+		},
+		{
+			name:       "finishing a book on another page",
+			from:       "reading",
+			page:       120,
+			method:     http.MethodPut,
+			body:       fmt.Sprintf(book, "finished", 200),
+			wantStatus: http.StatusUnprocessableEntity,
+			wantErrors: []string{"current_page"},
+		},
+		{
+			name:       "putting a book back with a page",
+			from:       "reading",
+			page:       120,
+			method:     http.MethodPut,
+			body:       fmt.Sprintf(book, "want_to_read", 30),
+			wantStatus: http.StatusUnprocessableEntity,
+			wantErrors: []string{"current_page"},
+		},
+		{
+			name:       "moving on while reading",
+			from:       "reading",
+			page:       120,
+			method:     http.MethodPut,
+			body:       fmt.Sprintf(book, "reading", 150),
+			wantStatus: http.StatusOK,
+			wantPage:   150,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, token := newBooksApplication(t)
+
+			rr, resp := send(t, handler, token, http.MethodPost, "/v1/books", "application/json", fmt.Sprintf(book, tt.from, tt.page))
+			if rr.Code != http.StatusCreated {
+				t.Fatalf("creating the book: %d %v", rr.Code, resp)
+			}
+
+			contentType := tt.contentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+
+			rr, resp = send(t, handler, token, tt.method, "/v1/books/1", contentType, tt.body)
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("status = %d %v; want %d", rr.Code, resp, tt.wantStatus)
+			}
+
+			for _, field := range tt.wantErrors {
+				if errs, _ := resp["error"].(map[string]any); errs[field] == nil {
+					t.Errorf("error = %v; want a message for %s", resp["error"], field)
+				}
+			}
+
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			//the saved book is checked, not just the response
+			_, resp = send(t, handler, token, http.MethodGet, "/v1/books/1", "", "")
+			if got := resp["book"].(map[string]any)["current_page"]; got != tt.wantPage {
+				t.Errorf("current_page = %v; want %v", got, tt.wantPage)
+			}
+		})
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	const book = `{"title": "The Hobbit", "published": 1937, "pages": 310, "genres": ["Fantasy"], "rating": 4.5, "authors": []}`
+
+	tests := []struct {
+		name        string
+		anonymous   bool
+		permissions []string //what the user has left; ignored when anonymous
+		method      string
+		wantStatus  int
+	}{
+		{name: "anonymous read", anonymous: true, method: http.MethodGet, wantStatus: http.StatusUnauthorized},
+		{name: "anonymous write", anonymous: true, method: http.MethodPost, wantStatus: http.StatusUnauthorized},
+		{name: "read only reads", permissions: []string{data.PermissionBooksRead}, method: http.MethodGet, wantStatus: http.StatusOK},
+		{name: "read only writes", permissions: []string{data.PermissionBooksRead}, method: http.MethodPost, wantStatus: http.StatusForbidden},
+		{name: "no permissions reads", method: http.MethodGet, wantStatus: http.StatusForbidden},
+		{name: "write only reads", permissions: []string{data.PermissionBooksWrite}, method: http.MethodGet, wantStatus: http.StatusForbidden},
+		{name: "write only writes", permissions: []string{data.PermissionBooksWrite}, method: http.MethodPost, wantStatus: http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, token := newBooksApp(t)
+			handler := app.handler()
+
+			//newBooksApp's user is the only one, so they have id 1
+			if err := app.models.Permissions.RemoveForUser(context.Background(), 1, data.AllPermissions...); err != nil {
+				t.Fatal(err)
+			}
+			if err := app.models.Permissions.AddForUser(context.Background(), 1, tt.permissions...); err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.anonymous {
+				token = ""
+			}
+
+			r := httptest.NewRequest(tt.method, "/v1/books", strings.NewReader(book))
+			r.Header.Set("Content-Type", "application/json")
+			if token != "" {
+				r.Header.Set("Authorization", "Bearer "+token)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, r)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("status = %d %s; want %d", rr.Code, rr.Body, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusUnauthorized && rr.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("WWW-Authenticate = %q; want Bearer", rr.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestGetThenPutBook(t *testing.T) {
+	handler, token := newBooksApplication(t)
+
+	//a rating of 0 and no authors are the values that used to be left out of the GET
+	const book = `{"title": "The Hobbit", "published": 1937, "pages": 310, "genres": ["Fantasy"],
+		"rating": 0, "status": "reading", "current_page": 40, "authors": []}`
+
+	rr, resp := send(t, handler, token, http.MethodPost, "/v1/books", "application/json", book)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("creating the book: %d %v", rr.Code, resp)
+	}
+
+	rr, resp = send(t, handler, token, http.MethodGet, "/v1/books/1", "", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("GET = %d %v", rr.Code, resp)
+	}
+
+	got := resp["book"].(map[string]any)
+	for _, field := range []string{"rating", "authors", "id", "started_at"} {
+		if _, ok := got[field]; !ok {
+			t.Errorf("GET book = %v; want %s in it", got, field)
+		}
+	}
+
+	//the book goes back exactly as it came, with only the page changed
+	got["current_page"] = 80
+	body, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := http.Header{"Content-Type": {"application/json"}, "If-Match": {rr.Header().Get("ETag")}}
+	rr, resp = sendHeader(t, handler, token, http.MethodPut, "/v1/books/1", header, string(body))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("PUT of the GET body = %d %v; want 200", rr.Code, resp)
+	}
+	if page := resp["book"].(map[string]any)["current_page"]; page != float64(80) {
+		t.Errorf("current_page = %v; want 80", page)
+	}
+
+	//the read-only members are ignored rather than applied
+	rr, resp = send(t, handler, token, http.MethodPut, "/v1/books/1", "application/json", strings.Replace(string(body), `"id":1`, `"id":99`, 1))
+	if rr.Code != http.StatusOK || resp["book"].(map[string]any)["id"] != float64(1) {
+		t.Errorf("PUT with another id = %d %v; want 200 and the id left as 1", rr.Code, resp)
+	}
+}
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+//this file holds the health check endpoints
+//  /v1/healthcheck/live says the process is up and able to answer; it never looks at the database
+//  /v1/healthcheck/ready says whether the server can do its job, and is a 503 when it can't so a load balancer stops sending it traffic
+//  /v1/healthcheck is a summary for people: the readiness status plus the build and how long the server has been up
+//both of the ones that look at the database share one readiness result that is checked at most every readyCacheTTL
+
+// readyTimeout is how long the readiness checks get before the database counts as down
+const readyTimeout = 2 * time.Second
+
+// the statuses the health checks report
+const (
+	statusAvailable = "available"
+	statusDegraded  = "degraded"
+)
+
+// readyCacheTTL is how long a readiness result is reused
+// the health checks need no token and skip the rate limiter, so without it every hit would ping the database and query the schema
+const readyCacheTTL = 5 * time.Second
+
+// checkResult is the outcome of one dependency check
+// it never has the error in it because the health checks are public; the error is logged instead
+type checkResult struct {
+	Status  string `json:"status"`
+	Latency string `json:"latency,omitempty"`
+	Version *int   `json:"version,omitempty"`
+	Latest  *int   `json:"latest,omitempty"`
+}
+
+// poolStats is sql.DBStats with JSON names; /v1/healthcheck/ready shows it so the pool can be seen next to the checks
+// the same numbers are on /metrics as the db_* gauges, which are just as public, so this gives nothing more away
+type poolStats struct {
+	MaxOpenConnections int    `json:"max_open_connections"`
+	OpenConnections    int    `json:"open_connections"`
+	InUse              int    `json:"in_use"`
+	Idle               int    `json:"idle"`
+	WaitCount          int64  `json:"wait_count"`
+	WaitDuration       string `json:"wait_duration"`
+}
+
+// readinessCache is the last readiness result and when it was checked
+type readinessCache struct {
+	mu      sync.Mutex
+	checked time.Time
+	ready   bool
+	checks  map[string]checkResult
+}
+
+// readiness reports whether the dependency checks passed, running them again when the last result is older than readyCacheTTL
+// the lock is held while they run so a burst of requests waits for one check instead of starting one each
+// the checks and the map they return are shared by every caller, so the map must not be changed
+func (app *application) readiness(ctx context.Context) (bool, map[string]checkResult) {
+	c := &app.health
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.checks == nil || time.Since(c.checked) >= readyCacheTTL {
+		//the result is shared, so the client that happened to start the check hanging up mustn't make the database look down
+		c.ready, c.checks = app.checkDependencies(context.WithoutCancel(ctx))
+		c.checked = time.Now()
+	}
+
+	return c.ready, c.checks
+}
+
+// checkDependencies pings the database and compares the schema version with the latest migration
+// the memory driver has nothing to check, so it is always ready
+func (app *application) checkDependencies(ctx context.Context) (bool, map[string]checkResult) {
+	checks := map[string]checkResult{}
+
+	if app.db == nil {
+		return true, checks
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, readyTimeout)
+	defer cancel()
+
+	start := time.Now()
+	if err := app.db.PingContext(ctx); err != nil {
+		app.logger.Warn("readiness check failed", "check", "database", "error", err)
+		checks["database"] = checkResult{Status: "down"}
+		return false, checks
+	}
+	checks["database"] = checkResult{Status: "up", Latency: time.Since(start).String()}
+
+	//the schema is only checked once the database is known to answer
+	latest := app.migrator.Latest()
+
+	version, err := app.migrator.VersionContext(ctx)
+	switch {
+	case err != nil:
+		app.logger.Warn("readiness check failed", "check", "migrations", "error", err)
+		checks["migrations"] = checkResult{Status: "unknown"}
+		return false, checks
+	case version < latest:
+		app.logger.Warn("readiness check failed", "check", "migrations", "version", version, "latest", latest)
+		checks["migrations"] = checkResult{Status: "pending", Version: &version, Latest: &latest}
+		return false, checks
+	}
+
+	checks["migrations"] = checkResult{Status: "current", Version: &version, Latest: &latest}
+	return true, checks
+}
+
+// healthcheck is the summary; it is always a 200 so it can be read even when the server isn't ready
+// the connection pool isn't in it; that is on /v1/healthcheck/ready and /metrics
+func (app *application) healthcheck(w http.ResponseWriter, r *http.Request) {
+	ready, checks := app.readiness(r.Context())
+
+	env := envelope{"status": statusAvailable, "checks": checks}
+	if !ready {
+		env["status"] = statusDegraded
+	}
+
+	env["system_info"] = map[string]string{
+		"environment": app.config.env,
+		"version":     version,
+		"commit":      buildCommit(),
+		"build_time":  buildTime,
+		"go_version":  runtime.Version(),
+		"uptime":      time.Since(app.started).Truncate(time.Second).String(),
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, env, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// liveHealthcheck only shows that the process can answer requests
+func (app *application) liveHealthcheck(w http.ResponseWriter, r *http.Request) {
+	if err := app.writeJSON(w, http.StatusOK, envelope{"status": "alive"}, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readyHealthcheck is a 200 when every dependency check passes and a 503 when any of them fails
+// the pool stats are read on every request rather than cached with the checks, because reading them doesn't touch the database
+func (app *application) readyHealthcheck(w http.ResponseWriter, r *http.Request) {
+	ready, checks := app.readiness(r.Context())
+
+	status := http.StatusOK
+	env := envelope{"status": statusAvailable, "checks": checks}
+	if !ready {
+		status = http.StatusServiceUnavailable
+		env["status"] = statusDegraded
+	}
+
+	if app.db != nil {
+		stats := app.db.Stats()
+
+		env["pool"] = poolStats{
+			MaxOpenConnections: stats.MaxOpenConnections,
+			OpenConnections:    stats.OpenConnections,
+			InUse:              stats.InUse,
+			Idle:               stats.Idle,
+			WaitCount:          stats.WaitCount,
+			WaitDuration:       stats.WaitDuration.String(),
+		}
+	}
+
+	if err := app.writeJSON(w, status, env, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// buildCommit is the commit set with -ldflags, or the one go build recorded when it was built from a git checkout
+func buildCommit() string {
+	if commit != "" {
+		return commit
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				return setting.Value
+			}
+		}
+	}
+
+	return "unknown"
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHealthcheck(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.driver = "sqlite"
+	cfg.dsn = filepath.Join(t.TempDir(), "readinglist.db")
+
+	var logs bytes.Buffer
+	srv, err := newServer(cfg, slog.New(slog.NewTextHandler(&logs, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := srv.app
+
+	get := func(path string) (int, map[string]any) {
+		t.Helper()
+
+		rr := httptest.NewRecorder()
+		srv.handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+
+		var body map[string]any
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatalf("body isn't JSON: %v", err)
+		}
+		return rr.Code, body
+	}
+
+	code, body := get("/v1/healthcheck")
+	if code != http.StatusOK || body["status"] != statusAvailable {
+		t.Fatalf("got %d %v; want 200 and %q", code, body, statusAvailable)
+	}
+	if _, ok := body["system_info"]; !ok {
+		t.Errorf("body = %v; want the system info", body)
+	}
+	if _, ok := body["pool"]; ok {
+		t.Errorf("body = %v; want no pool stats", body)
+	}
+
+	//the readiness check shows the pool; sqlite's is always one connection
+	code, body = get("/v1/healthcheck/ready")
+	if code != http.StatusOK {
+		t.Fatalf("got %d %v; want 200", code, body)
+	}
+	if pool, _ := body["pool"].(map[string]any); pool["max_open_connections"] != float64(1) {
+		t.Errorf("pool = %v; want max_open_connections 1", body["pool"])
+	}
+
+	//with the database gone the cached result is still shown until it is older than readyCacheTTL
+	app.db.Close()
+
+	if code, body := get("/v1/healthcheck/ready"); code != http.StatusOK {
+		t.Errorf("got %d %v; want the cached 200", code, body)
+	}
+
+	app.health.checked = time.Now().Add(-readyCacheTTL)
+
+	code, body = get("/v1/healthcheck/ready")
+	if code != http.StatusServiceUnavailable || body["status"] != statusDegraded {
+		t.Fatalf("got %d %v; want 503 and %q", code, body, statusDegraded)
+	}
+
+	//the error is only logged; the response just says the database is down
+	checks := body["checks"].(map[string]any)
+	if got := checks["database"]; got.(map[string]any)["status"] != "down" || len(got.(map[string]any)) != 1 {
+		t.Errorf("database check = %v; want only the status down", got)
+	}
+	if !strings.Contains(logs.String(), "database is closed") {
+		t.Errorf("logs = %q; want the ping error", logs.String())
+	}
+
+	//the summary is still a 200 so it can be read while the server isn't ready
+	if code, body := get("/v1/healthcheck"); code != http.StatusOK || body["status"] != statusDegraded {
+		t.Errorf("got %d %v; want 200 and %q", code, body, statusDegraded)
+	}
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"readinglist/internal/validator"
+)
+
+// the type below is part of making an envelope for JSON data
+// this envelope type will be used to collect the JSON data within a named object which can make parsing easier
+type envelope map[string]any
+
+// Credit: Alex Edwards, Let's Go Further
+// This was added to replace duplicated code in the handlers so as to observed the DRY principle
+func (app *application) writeJSON(w http.ResponseWriter, status int, data envelope, headers http.Header) error {
+	js, err := json.MarshalIndent(data, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	js = append(js, '\n')
+
+	for key, value := range headers {
+		w.Header()[key] = value
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	//the status has already been sent, so a failed write can't be turned into an error response; it is only logged
+	if _, err := w.Write(js); err != nil {
+		app.logger.Warn("writing response body", "error", err)
+	}
+
+	return nil
+}
+
+// this function replaces having an unmarshall function inside handlers.go
+// it also helps protect the web service by setting a maximum allowed bytes
+// and it disallows unknown fields, meaning you can pass in json fields that aren't part of the struct that is defined on the interface
+// that's why this uses the decoder instead of just unmarshall
+func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	maxBytes := 1_048_576
+	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes)) //sets max bytes
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields() //disallows unknown fields
+
+	if err := dec.Decode(dst); err != nil {
+		return err
+	}
+
+	err := dec.Decode(&struct{}{})
+	if err != io.EOF {
+		return errors.New("body must only contain a single JSON object")
+	}
+
+	return nil
+}
+
+// the helpers below read values out of the query string
+// each one takes a default value that is returned when the key isn't in the query string
+
+// readString returns the string value for the key or the default value
+func (app *application) readString(qs url.Values, key string, defaultValue string) string {
+	s := qs.Get(key)
+
+	if s == "" {
+		return defaultValue
+	}
+
+	return s
+}
+
+// readCSV splits a comma-separated value like ?genres=fiction,fantasy into a slice of strings
+func (app *application) readCSV(qs url.Values, key string, defaultValue []string) []string {
+	csv := qs.Get(key)
+
+	if csv == "" {
+		return defaultValue
+	}
+
+	return strings.Split(csv, ",")
+}
+
+// readInt converts the value for the key into an int
+// if the value isn't a whole number an error is added to the validator and the default value is returned
+func (app *application) readInt(qs url.Values, key string, defaultValue int, v *validator.Validator) int {
+	s := qs.Get(key)
+
+	if s == "" {
+		return defaultValue
+	}
+
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		v.AddError(key, "must be an integer value")
+		return defaultValue
+	}
+
+	return i
+}
+
+// readIDParam reads the {id} wildcard from the route pattern, such as the 5 in /v1/books/5
+// anything that isn't a positive whole number is an error, which every caller answers with a 404 because no record can have that id
+func (app *application) readIDParam(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id < 1 {
+		return 0, errors.New("invalid id parameter")
+	}
+
+	return id, nil
+}
+
+// etag turns a book version into the value sent in the ETag header, for example "3"
+// ETag values are quoted strings, which is why the quotes are part of the value
+func etag(version int32) string {
+	return fmt.Sprintf("%q", strconv.Itoa(int(version)))
+}
+
+// ifMatch is the parsed If-Match header
+// versions only holds the strong ETags that name a book version, weak or unknown tags are left out because they never match
+type ifMatch struct {
+	present  bool
+	any      bool
+	versions []int32
+}
+
+// matches reports whether a book at this version passes the If-Match check
+// a missing header or "*" lets any version through
+func (m ifMatch) matches(version int32) bool {
+	return !m.present || m.any || slices.Contains(m.versions, version)
+}
+
+// readIfMatch parses the If-Match header, which is "*" or a comma separated list of ETags
+// RFC 9110 says If-Match uses the strong comparison, so a weak tag like W/"3" is parsed but never matches
+func (app *application) readIfMatch(r *http.Request) (ifMatch, error) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+
+	if value == "" {
+		return ifMatch{}, nil
+	}
+
+	if value == "*" {
+		return ifMatch{present: true, any: true}, nil
+	}
+
+	malformed := errors.New(`If-Match must be "*" or a list of ETags such as "3", "4"`)
+	m := ifMatch{present: true}
+
+	for value != "" {
+		weak := strings.HasPrefix(value, "W/")
+		value = strings.TrimPrefix(value, "W/")
+
+		//an ETag is quoted and can't contain a quote, so the tag ends at the next one
+		if !strings.HasPrefix(value, `"`) {
+			return ifMatch{}, malformed
+		}
+		tag, rest, found := strings.Cut(value[1:], `"`)
+		if !found {
+			return ifMatch{}, malformed
+		}
+
+		if version, err := strconv.ParseInt(tag, 10, 32); !weak && err == nil && version > 0 {
+			m.versions = append(m.versions, int32(version))
+		}
+
+		//after each tag there is either nothing left or a comma and the next tag
+		rest = strings.TrimSpace(rest)
+		if rest != "" {
+			if !strings.HasPrefix(rest, ",") {
+				return ifMatch{}, malformed
+			}
+			rest = strings.TrimSpace(rest[1:])
+			if rest == "" {
+				return ifMatch{}, malformed
+			}
+		}
+		value = rest
+	}
+
+	return m, nil
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"database/sql"
+	"expvar"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	"readinglist/internal/metrics"
+	"readinglist/internal/middleware"
+)
+
+//this file holds the metrics served at /metrics in the Prometheus text format and the values published at /debug/vars
+
+// appMetrics are the request metrics recorded by middleware.RecordMetrics
+type appMetrics struct {
+	registry *metrics.Registry
+	requests *middleware.RequestMetrics
+}
+
+// newAppMetrics makes the request metrics, and the connection pool metrics when there is a database
+// db is nil for the memory driver
+func newAppMetrics(db *sql.DB) *appMetrics {
+	registry := metrics.NewRegistry()
+
+	m := &appMetrics{
+		registry: registry,
+		requests: middleware.NewRequestMetrics(registry),
+	}
+
+	if db != nil {
+		registerDBStats(registry, db)
+	}
+
+	return m
+}
+
+// registerDBStats adds the sql.DBStats of the pool; the values are read from the pool every time the metrics are scraped
+func registerDBStats(registry *metrics.Registry, db *sql.DB) {
+	stat := func(fn func(s sql.DBStats) float64) func() float64 {
+		return func() float64 { return fn(db.Stats()) }
+	}
+
+	registry.NewGaugeFunc("db_max_open_connections", "Maximum number of open connections to the database.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }))
+	registry.NewGaugeFunc("db_open_connections", "Number of established connections, both in use and idle.",
+		stat(func(s sql.DBStats) float64 { return float64(s.OpenConnections) }))
+	registry.NewGaugeFunc("db_in_use_connections", "Number of connections in use.",
+		stat(func(s sql.DBStats) float64 { return float64(s.InUse) }))
+	registry.NewGaugeFunc("db_idle_connections", "Number of idle connections.",
+		stat(func(s sql.DBStats) float64 { return float64(s.Idle) }))
+	registry.NewCounterFunc("db_wait_count_total", "Number of times a query had to wait for a free connection.",
+		stat(func(s sql.DBStats) float64 { return float64(s.WaitCount) }))
+	registry.NewCounterFunc("db_wait_duration_seconds_total", "Total time spent waiting for a free connection.",
+		stat(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }))
+	registry.NewCounterFunc("db_max_idle_closed_total", "Number of connections closed because of the maximum number of idle connections.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }))
+	registry.NewCounterFunc("db_max_idle_time_closed_total", "Number of connections closed because they were idle for too long.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) }))
+	registry.NewCounterFunc("db_max_lifetime_closed_total", "Number of connections closed because they reached their maximum lifetime.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }))
+}
+
+// publishExpvars adds the values shown at /debug/vars
+// expvar panics if a name is published twice, so only the first server made in a process publishes anything
+func publishExpvars(db *sql.DB) {
+	expvarsOnce.Do(func() { publishExpvarsOnce(db) })
+}
+
+var (
+	expvarsOnce sync.Once
+	//expvarNames are the names publishExpvars published, in the order expvarHandler shows them
+	expvarNames []string
+)
+
+func publishExpvarsOnce(db *sql.DB) {
+	publish := func(name string, v expvar.Var) {
+		expvar.Publish(name, v)
+		expvarNames = append(expvarNames, name)
+	}
+
+	publish("version", expvar.Func(func() any {
+		return version
+	}))
+
+	publish("goroutines", expvar.Func(func() any {
+		return runtime.NumGoroutine()
+	}))
+
+	publish("timestamp", expvar.Func(func() any {
+		return time.Now().Unix()
+	}))
+
+	if db != nil {
+		publish("database", expvar.Func(func() any {
+			return db.Stats()
+		}))
+	}
+}
+
+// expvarHandler serves the values publishExpvars published as one JSON object
+// it is used instead of expvar.Handler because that also shows cmdline, which has the -db-dsn and -api-token flags in it
+func expvarHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		fmt.Fprint(w, "{\n")
+		for i, name := range expvarNames {
+			if i > 0 {
+				fmt.Fprint(w, ",\n")
+			}
+			fmt.Fprintf(w, "%q: %s", name, expvar.Get(name))
+		}
+		fmt.Fprint(w, "\n}\n")
+	})
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExpvarHandler(t *testing.T) {
+	publishExpvars(nil)
+
+	rr := httptest.NewRecorder()
+	expvarHandler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+
+	var vars map[string]json.RawMessage
+	if err := json.NewDecoder(rr.Body).Decode(&vars); err != nil {
+		t.Fatalf("body isn't JSON: %v", err)
+	}
+
+	for _, name := range []string{"version", "goroutines", "timestamp"} {
+		if _, ok := vars[name]; !ok {
+			t.Errorf("%q is missing from %v", name, vars)
+		}
+	}
+
+	//cmdline has the -db-dsn and -api-token flags in it and memstats is noise, so neither is served
+	for _, name := range []string{"cmdline", "memstats"} {
+		if _, ok := vars[name]; ok {
+			t.Errorf("%q is served", name)
+		}
+	}
+}
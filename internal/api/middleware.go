@@ -0,0 +1,344 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"readinglist/internal/data"
+	"readinglist/internal/middleware"
+	"readinglist/internal/requestid"
+	"readinglist/internal/validator"
+)
+
+//this file holds the middleware; each one takes the next handler in the chain and returns a handler that wraps it
+//the middleware the api shares with the web app, such as the request log, is in internal/middleware
+
+// requestID uses the X-Request-ID sent by the client, such as the web app, or makes a new one when there isn't a usable one
+// the id is sent back in the response and put in the request context for the logs and error bodies
+func (app *application) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+
+		w.Header().Set(requestid.Header, id)
+
+		r = r.WithContext(requestid.NewContext(r.Context(), id))
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// recoverPanic turns a panic in a handler into a 500 with the usual JSON error body; see middleware.RecoverPanic
+func (app *application) recoverPanic(next http.Handler) http.Handler {
+	return middleware.RecoverPanic(app.logger, func(w http.ResponseWriter, r *http.Request) {
+		message := "the server encountered a problem and could not process your request"
+		app.errorResponse(w, r, http.StatusInternalServerError, message)
+	}, next)
+}
+
+// enableCORS lets browsers on the -cors-trusted-origins call the api, with cookies and Authorization headers included
+// a request from any other origin gets no CORS headers, so the browser keeps its response from the page that made it
+// preflight requests, which browsers send before a PUT, PATCH or DELETE or a request with an Authorization header, are answered here
+// and never reach authenticate or the rate limiter
+func (app *application) enableCORS(next http.Handler) http.Handler {
+	//the origin a browser sends never ends in a slash, so one on the end of a trusted origin is dropped
+	trusted := make(map[string]bool, len(app.config.cors.trustedOrigins))
+	for _, origin := range app.config.cors.trustedOrigins {
+		trusted[strings.TrimSuffix(origin, "/")] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		//the response depends on the origin, so caches have to keep a copy for each one
+		w.Header().Add("Vary", "Origin")
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+
+		origin := r.Header.Get("Origin")
+
+		if origin != "" && trusted[origin] {
+			//with credentials allowed the origin has to be named; the wildcard * isn't accepted by browsers
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+			//these are the response headers a page is allowed to read; ETag is needed to send If-Match back
+			w.Header().Set("Access-Control-Expose-Headers", "ETag, Accept-Patch, Retry-After, X-Request-ID")
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Set("Access-Control-Allow-Methods", "OPTIONS, PUT, PATCH, DELETE")
+				w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, If-Match, X-Request-ID")
+				w.Header().Set("Access-Control-Max-Age", "60")
+
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authenticate works out who is making the request from the Authorization: Bearer <token> header
+// a request without the header carries on as the AnonymousUser, and a request with a bad token is stopped with a 401
+func (app *application) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		//the response depends on the Authorization header, so caches have to keep a copy for each value of it
+		w.Header().Add("Vary", "Authorization")
+
+		authorizationHeader := r.Header.Get("Authorization")
+
+		if authorizationHeader == "" {
+			r = app.contextSetUser(r, data.AnonymousUser)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		headerParts := strings.Split(authorizationHeader, " ")
+		if len(headerParts) != 2 || headerParts[0] != "Bearer" {
+			app.invalidAuthenticationTokenResponse(w, r)
+			return
+		}
+
+		token := headerParts[1]
+
+		v := validator.New()
+
+		if data.ValidateTokenPlaintext(v, token); !v.Valid() {
+			app.invalidAuthenticationTokenResponse(w, r)
+			return
+		}
+
+		user, err := app.models.Users.GetForToken(r.Context(), data.ScopeAuthentication, token)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.invalidAuthenticationTokenResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		r = app.contextSetUser(r, user)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitByIP limits the requests that aren't counted against a user on the address they came from
+// an anonymous request is counted straight away; a request with a token is only counted when authenticate turns the token down,
+// so a signed-in user isn't charged twice and users behind one address don't use up each other's requests
+// bad tokens have a bucket of their own and it sits in front of authenticate, so an address that has used that bucket up
+// is stopped before its next token costs a database lookup, even if that token is good
+func (app *application) rateLimitByIP(next http.Handler) http.Handler {
+	limiter := app.newClientLimiter()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !app.rateLimited(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if r.Header.Get("Authorization") == "" {
+			if delay := limiter.take("ip:" + ip); delay > 0 {
+				app.rateLimitExceededResponse(w, r, int(math.Ceil(delay.Seconds())))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if delay := limiter.wait("bad-token:" + ip); delay > 0 {
+			app.rateLimitExceededResponse(w, r, int(math.Ceil(delay.Seconds())))
+			return
+		}
+
+		rec := middleware.NewStatusRecorder(w)
+		next.ServeHTTP(rec, r)
+
+		//with a token in the request, only authenticate answers 401, so this was a bad token
+		if rec.Status() == http.StatusUnauthorized {
+			limiter.take("bad-token:" + ip)
+		}
+	})
+}
+
+// rateLimitByUser limits signed-in users on their user id, so a user gets one bucket wherever they connect from
+// anonymous requests are left to rateLimitByIP
+func (app *application) rateLimitByUser(next http.Handler) http.Handler {
+	limiter := app.newClientLimiter()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := app.contextGetUser(r)
+		if !app.rateLimited(r) || user.IsAnonymous() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if delay := limiter.take(fmt.Sprintf("user:%d", user.ID)); delay > 0 {
+			app.rateLimitExceededResponse(w, r, int(math.Ceil(delay.Seconds())))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimited reports whether the request goes through the rate limiter at all
+// the monitoring endpoints and the web app's calls in readinglist all skip it, see unlimitedPaths and Server.Handler
+func (app *application) rateLimited(r *http.Request) bool {
+	inProcess, _ := r.Context().Value(inProcessContextKey).(bool)
+	return app.config.limiter.enabled && !unlimitedPaths[r.URL.Path] && !inProcess
+}
+
+// clientLimiter gives every client its own token bucket of -limiter-burst requests that refills at -limiter-rps requests a second
+type clientLimiter struct {
+	rps   float64
+	burst int
+
+	mu      sync.Mutex
+	clients map[string]*limitedClient
+}
+
+type limitedClient struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// newClientLimiter makes an empty clientLimiter from the config
+// it starts a goroutine that removes the clients that haven't been seen for three minutes so the map doesn't keep growing
+// the goroutine stops when the server shuts down
+func (app *application) newClientLimiter() *clientLimiter {
+	l := &clientLimiter{
+		rps:     app.config.limiter.rps,
+		burst:   app.config.limiter.burst,
+		clients: make(map[string]*limitedClient),
+	}
+
+	app.background(func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-app.done:
+				return
+			case <-ticker.C:
+			}
+
+			l.mu.Lock()
+
+			for key, client := range l.clients {
+				if time.Since(client.lastSeen) > 3*time.Minute {
+					delete(l.clients, key)
+				}
+			}
+
+			l.mu.Unlock()
+		}
+	})
+
+	return l
+}
+
+// client returns the bucket for key, making it the first time key is seen; l.mu must be held
+func (l *clientLimiter) client(key string) *rate.Limiter {
+	if _, found := l.clients[key]; !found {
+		l.clients[key] = &limitedClient{limiter: rate.NewLimiter(rate.Limit(l.rps), l.burst)}
+	}
+
+	l.clients[key].lastSeen = time.Now()
+	return l.clients[key].limiter
+}
+
+// take uses up a token from key's bucket
+// when the bucket is empty nothing is taken and it returns how long until the next token, which goes in the 429
+func (l *clientLimiter) take(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	//a reservation says how long until the next token is free; it is cancelled so a refused request doesn't use one up
+	reservation := l.client(key).Reserve()
+	delay := reservation.Delay()
+	if delay > 0 {
+		reservation.Cancel()
+	}
+
+	return delay
+}
+
+// wait is take without using up the token: it returns how long until key's bucket has one, or 0 if it has one now
+func (l *clientLimiter) wait(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	limiter := l.client(key)
+	if limiter.TokensAt(now) >= 1 {
+		return 0
+	}
+
+	reservation := limiter.ReserveN(now, 1)
+	delay := reservation.DelayFrom(now)
+	reservation.CancelAt(now)
+
+	return delay
+}
+
+// unlimitedPaths are the monitoring endpoints; they are polled from one address all the time, so they skip the rate limiter
+var unlimitedPaths = map[string]bool{
+	"/v1/healthcheck":       true,
+	"/v1/healthcheck/live":  true,
+	"/v1/healthcheck/ready": true,
+	"/metrics":              true,
+}
+
+// requireAuthenticatedUser stops anonymous requests with a 401 before they reach the handler
+func (app *application) requireAuthenticatedUser(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := app.contextGetUser(r)
+
+		if user.IsAnonymous() {
+			app.authenticationRequiredResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// requirePermission only lets the request through if the user has the permission code, for example "books:write"
+// anonymous users get a 401 from requireAuthenticatedUser first, and users without the permission get a 403
+func (app *application) requirePermission(code string, next http.HandlerFunc) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		user := app.contextGetUser(r)
+
+		permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		if !permissions.Include(code) {
+			app.notPermittedResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+
+	return app.requireAuthenticatedUser(fn)
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"readinglist/internal/migrate"
+)
+
+// runMigrate handles the migrate subcommand
+// the args are everything after the word migrate, for example "up" or "goto 2"
+func runMigrate(cfg config, logger *slog.Logger, args []string) error {
+	if cfg.driver == "memory" {
+		return errors.New("the memory driver has no schema to migrate")
+	}
+
+	if len(args) == 0 {
+		return errors.New("usage: migrate up|down|status|goto N")
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := migrate.New(db, cfg.driver)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		err = migrator.Up()
+
+	case "down":
+		err = migrator.Down()
+
+	case "goto":
+		if len(args) != 2 {
+			return errors.New("usage: migrate goto N")
+		}
+
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil || version < 0 {
+			return fmt.Errorf("invalid migration version %q", args[1])
+		}
+
+		err = migrator.Goto(version)
+
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+
+		for _, status := range statuses {
+			if status.Applied {
+				fmt.Printf("%06d %-30s applied %s\n", status.Version, status.Name, status.AppliedAt.Format("2006-01-02 15:04:05"))
+			} else {
+				fmt.Printf("%06d %-30s pending\n", status.Version, status.Name)
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown migrate command %q; expected up, down, status or goto N", args[0])
+	}
+
+	if err != nil {
+		return err
+	}
+
+	version, err := migrator.Version()
+	if err != nil {
+		return err
+	}
+
+	logger.Info("database schema migrated", "version", version)
+	return nil
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"readinglist/internal/data"
+	"readinglist/internal/validator"
+)
+
+const permissionsUsage = "usage: permissions grant|revoke <email> <code>... or permissions list <email>"
+
+// runPermissions handles the permissions subcommand, which is how an account's permissions are changed
+// every new user gets data.DefaultPermissions, so an account's list is frozen by running
+// permissions revoke <email> books:write
+// lists aren't shared, so this only stops the user changing their own books; it doesn't let them read anyone else's
+// ctx stops the queries when the command is interrupted
+// the args are everything after the word permissions, for example "list reader@example.com"
+func runPermissions(ctx context.Context, cfg config, logger *slog.Logger, args []string) error {
+	if cfg.driver == "memory" {
+		return errors.New("the memory driver keeps no users between runs")
+	}
+
+	if len(args) < 2 {
+		return errors.New(permissionsUsage)
+	}
+
+	command, email, codes := args[0], args[1], args[2:]
+
+	switch command {
+	case "grant", "revoke":
+		if len(codes) == 0 {
+			return errors.New(permissionsUsage)
+		}
+	case "list":
+		if len(codes) != 0 {
+			return errors.New(permissionsUsage)
+		}
+	default:
+		return fmt.Errorf("unknown permissions command %q; expected grant, revoke or list", command)
+	}
+
+	//the stores ignore codes they don't know, so a typo is caught here instead of silently doing nothing
+	for _, code := range codes {
+		if !validator.PermittedValue(code, data.AllPermissions...) {
+			return fmt.Errorf("unknown permission %q; expected %s", code, strings.Join(data.AllPermissions, " or "))
+		}
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	models := data.NewModels(db)
+	if cfg.driver == "sqlite" {
+		models = data.NewSQLiteModels(db)
+	}
+
+	user, err := models.Users.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return fmt.Errorf("there is no user with the email address %q", email)
+		}
+		return err
+	}
+
+	switch command {
+	case "grant":
+		err = models.Permissions.AddForUser(ctx, user.ID, codes...)
+	case "revoke":
+		err = models.Permissions.RemoveForUser(ctx, user.ID, codes...)
+	}
+	if err != nil {
+		return err
+	}
+
+	permissions, err := models.Permissions.GetAllForUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	if command == "list" {
+		fmt.Println(strings.Join(permissions, " "))
+		return nil
+	}
+
+	logger.Info("user permissions changed", "email", user.Email, "permissions", permissions)
+	return nil
+}
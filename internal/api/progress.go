@@ -0,0 +1,141 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"readinglist/internal/data"
+	"readinglist/internal/validator"
+)
+
+//this file holds the handlers for a book's reading progress at /v1/books/{id}/progress
+
+// setBookStatus moves the book to a new status and adds a validation error if the move isn't allowed
+func (app *application) setBookStatus(v *validator.Validator, book *data.Book, status string) {
+	from := book.Status
+
+	err := book.SetStatus(status, time.Now())
+	switch {
+	case err == nil:
+	case !validator.PermittedValue(status, data.ReadingStatuses...):
+		v.AddError("status", "must be want_to_read, reading, finished or abandoned")
+	default:
+		v.AddError("status", fmt.Sprintf("a %s book can't be marked as %s", from, status))
+	}
+}
+
+// listProgress returns the reading sessions logged for a book, the most recent first
+func (app *application) listProgress(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	//this makes sure a missing book is a 404 rather than an empty list
+	if _, err := app.models.Books.Get(r.Context(), id, app.contextGetUser(r).ID); err != nil {
+		app.modelErrorResponse(w, r, err)
+		return
+	}
+
+	sessions, err := app.models.Progress.GetForBook(r.Context(), id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, envelope{"sessions": sessions}, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// logProgress records a reading session and moves the book's current page on by the pages that were read
+// logging a session starts a book that hasn't been started, and reaching the last page finishes it
+func (app *application) logProgress(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	book, err := app.models.Books.Get(r.Context(), id, app.contextGetUser(r).ID)
+	if err != nil {
+		app.modelErrorResponse(w, r, err)
+		return
+	}
+
+	//the date is optional and defaults to today; it is a plain date like 2024-05-01
+	var input struct {
+		PagesRead int    `json:"pages_read"`
+		Minutes   int    `json:"minutes"`
+		Date      string `json:"date"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	readOn := time.Now().UTC().Truncate(24 * time.Hour)
+	if input.Date != "" {
+		readOn, err = time.Parse(time.DateOnly, input.Date)
+		if err != nil {
+			v.AddError("date", "must be a date in the form YYYY-MM-DD")
+		}
+	}
+
+	session := &data.ReadingSession{
+		BookID:    book.ID,
+		PagesRead: input.PagesRead,
+		Minutes:   input.Minutes,
+		ReadOn:    readOn,
+	}
+
+	data.ValidateReadingSession(v, session)
+	v.Check(book.Status != data.StatusFinished, "status", "the book is finished; mark it as reading to start it again")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	//want_to_read and abandoned books can both move to reading, so this can't fail
+	if err := book.SetStatus(data.StatusReading, readOn); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	book.CurrentPage += session.PagesRead
+	if book.CurrentPage >= book.Pages {
+		if err := book.SetStatus(data.StatusFinished, readOn); err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	//the book is saved first so an edit conflict stops the session being logged twice when the client retries
+	if err := app.models.Books.Update(r.Context(), book); err != nil {
+		app.modelErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.models.Progress.Insert(r.Context(), session); err != nil {
+		app.modelErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.models.Authors.LoadForBooks(r.Context(), []*data.Book{book}); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", etag(book.Version))
+
+	if err := app.writeJSON(w, http.StatusCreated, envelope{"session": session, "book": book}, headers); err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
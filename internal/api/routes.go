@@ -0,0 +1,98 @@
+package api
+
+import (
+	"net/http"
+
+	"readinglist/internal/data"
+	"readinglist/internal/middleware"
+)
+
+// This instantiates all of the routes
+// handler wraps the mux in the middleware, such as authenticate so every handler can find out who is making the request
+// this is a method tied to application (it takes in app, defined in api.go as an instance of the struct type application) that returns a new ServeMux
+// each pattern starts with the method it answers, and {id} is read in the handlers with readIDParam
+// a path that matches with the wrong method gets a 405 from the mux, which muxErrors turns into JSON
+func (app *application) route() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/healthcheck", app.healthcheck) // this is an route
+	mux.HandleFunc("GET /v1/healthcheck/live", app.liveHealthcheck)
+	mux.HandleFunc("GET /v1/healthcheck/ready", app.readyHealthcheck)
+	// Endpoints are functions available through the API
+	// A route is the name you use to access endpoints, used in the URL
+
+	//reading a book needs the books:read permission and changing one needs books:write
+	mux.HandleFunc("GET /v1/books", app.requirePermission(data.PermissionBooksRead, app.listBooks))    // Gets all books
+	mux.HandleFunc("POST /v1/books", app.requirePermission(data.PermissionBooksWrite, app.createBook)) // Creates new book
+	//1st arg is the route; 2nd arg is the handler function (endpoint)
+
+	mux.HandleFunc("GET /v1/books/{id}", app.requirePermission(data.PermissionBooksRead, app.getBook))
+	mux.HandleFunc("PUT /v1/books/{id}", app.requirePermission(data.PermissionBooksWrite, app.updateBook))
+	mux.HandleFunc("PATCH /v1/books/{id}", app.requirePermission(data.PermissionBooksWrite, app.patchBook))
+	mux.HandleFunc("DELETE /v1/books/{id}", app.requirePermission(data.PermissionBooksWrite, app.deleteBook))
+
+	mux.HandleFunc("GET /v1/books/{id}/progress", app.requirePermission(data.PermissionBooksRead, app.listProgress))
+	mux.HandleFunc("POST /v1/books/{id}/progress", app.requirePermission(data.PermissionBooksWrite, app.logProgress))
+
+	//authors are part of a reading list, so they need the same permissions as books; changing or deleting an author changes the books they are on
+	mux.HandleFunc("GET /v1/authors", app.requirePermission(data.PermissionBooksRead, app.listAuthors))
+	mux.HandleFunc("POST /v1/authors", app.requirePermission(data.PermissionBooksWrite, app.createAuthor))
+	mux.HandleFunc("GET /v1/authors/{id}", app.requirePermission(data.PermissionBooksRead, app.getAuthor))
+	mux.HandleFunc("PUT /v1/authors/{id}", app.requirePermission(data.PermissionBooksWrite, app.updateAuthor))
+	mux.HandleFunc("DELETE /v1/authors/{id}", app.requirePermission(data.PermissionBooksWrite, app.deleteAuthor))
+	mux.HandleFunc("GET /v1/authors/{id}/books", app.requirePermission(data.PermissionBooksRead, app.listAuthorBooks))
+
+	mux.HandleFunc("POST /v1/users", app.registerUser)                              // Registers a new user
+	mux.HandleFunc("POST /v1/tokens/authentication", app.createAuthenticationToken) // Swaps an email and password for a bearer token
+
+	//this is scraped by Prometheus; /debug/vars isn't served here but on -debug-addr, see serveDebug
+	mux.Handle("GET /metrics", app.metrics.registry.Handler())
+
+	return mux //This returns the mux and all the handlers associated with it
+}
+
+// handler wraps the routes in the middleware, the outermost first:
+// requestID so every log line and error body has the id, middleware.RecordMetrics and middleware.LogRequest so they see the final status,
+// recoverPanic so a panic still gets a response, enableCORS before authenticate so a preflight doesn't need a token,
+// rateLimitByIP before authenticate so a flood of bad tokens never reaches the database,
+// and rateLimitByUser after authenticate so it can tell users apart; each request is only counted by one of the two
+func (app *application) handler() http.Handler {
+	mux := app.route()
+
+	return app.requestID(middleware.RecordMetrics(app.metrics.requests, mux, middleware.LogRequest(app.logger, app.recoverPanic(app.enableCORS(app.rateLimitByIP(app.authenticate(app.rateLimitByUser(app.muxErrors(mux)))))))))
+}
+
+// muxErrors sends the mux's own 404 and 405 responses as JSON errors like every other response from the api
+// the mux has already worked out the Allow header for a 405, so it is copied across
+func (app *application) muxErrors(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		//a request that matched a pattern, or gets a redirect to a cleaned-up path, is handled as normal
+		h, pattern := mux.Handler(r)
+		if pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &discardRecorder{header: make(http.Header)}
+		h.ServeHTTP(rec, r)
+
+		switch rec.status {
+		case http.StatusMethodNotAllowed:
+			w.Header().Set("Allow", rec.header.Get("Allow"))
+			app.methodNotAllowedResponse(w, r)
+		default:
+			app.notFoundResponse(w, r)
+		}
+	})
+}
+
+// discardRecorder keeps the headers and status a handler sets and throws its body away
+type discardRecorder struct {
+	header http.Header
+	status int
+}
+
+func (rec *discardRecorder) Header() http.Header { return rec.header }
+
+func (rec *discardRecorder) Write(b []byte) (int, error) { return len(b), nil }
+
+func (rec *discardRecorder) WriteHeader(status int) { rec.status = status }
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMuxErrors(t *testing.T) {
+	app := newTestApplication(t, config{})
+
+	//the handler sends the id back so the test can check readIDParam too
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/books/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+		app.writeJSON(w, http.StatusOK, envelope{"id": id}, nil)
+	})
+	mux.HandleFunc("DELETE /v1/books/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantAllow  string
+	}{
+		{name: "matched", method: http.MethodGet, path: "/v1/books/5", wantStatus: http.StatusOK},
+		{name: "invalid id", method: http.MethodGet, path: "/v1/books/abc", wantStatus: http.StatusNotFound},
+		{name: "zero id", method: http.MethodGet, path: "/v1/books/0", wantStatus: http.StatusNotFound},
+		{name: "extra path segment", method: http.MethodGet, path: "/v1/books/5/extra", wantStatus: http.StatusNotFound},
+		{name: "trailing slash", method: http.MethodGet, path: "/v1/books/5/", wantStatus: http.StatusNotFound},
+		{name: "unknown path", method: http.MethodGet, path: "/v1/nothing", wantStatus: http.StatusNotFound},
+		{name: "unknown method", method: http.MethodPost, path: "/v1/books/5", wantStatus: http.StatusMethodNotAllowed, wantAllow: "DELETE, GET, HEAD"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			app.muxErrors(mux).ServeHTTP(rr, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("status = %d; want %d", rr.Code, tt.wantStatus)
+			}
+
+			if got := rr.Header().Get("Allow"); got != tt.wantAllow {
+				t.Errorf("Allow = %q; want %q", got, tt.wantAllow)
+			}
+
+			if got := rr.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type = %q; want %q", got, "application/json")
+			}
+
+			var body map[string]any
+			if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+				t.Fatalf("body isn't JSON: %v", err)
+			}
+
+			if _, ok := body["error"]; ok != (tt.wantStatus != http.StatusOK) {
+				t.Errorf("body = %v; want an error only for a failed request", body)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+)
+
+// serve runs the http server until ctx is cancelled, which is when the process gets SIGINT or SIGTERM, and then shuts it down gracefully:
+// the server stops taking new requests and finishes the ones in flight, then the background goroutines are stopped and waited for
+// everything has to be done within -shutdown-timeout; the database is closed by Run after serve returns
+func (app *application) serve(ctx context.Context, handler http.Handler) error {
+	//the debug server is started first so an address that is already in use stops the api before it starts taking requests
+	stopDebug, err := app.serveDebug()
+	if err != nil {
+		return err
+	}
+	defer stopDebug()
+
+	addr := fmt.Sprintf(":%d", app.config.port)
+
+	srv := &http.Server{
+		Addr:         addr,
+		Handler:      handler,
+		ErrorLog:     slog.NewLogLogger(app.logger.Handler(), slog.LevelError),
+		IdleTimeout:  app.config.server.idleTimeout,
+		ReadTimeout:  app.config.server.readTimeout,
+		WriteTimeout: app.config.server.writeTimeout,
+	}
+
+	//the goroutine below waits for ctx to be cancelled and sends the result of the shutdown back on this channel
+	//it has room for the result so the goroutine isn't left stuck when the server failed to start and nobody is waiting
+	shutdownError := make(chan error, 1)
+
+	go func() {
+		<-ctx.Done()
+
+		app.logger.Info("shutting down the server", "timeout", app.config.shutdownTimeout.String())
+
+		ctx, cancel := context.WithTimeout(context.Background(), app.config.shutdownTimeout)
+		defer cancel()
+
+		//Shutdown makes ListenAndServe return http.ErrServerClosed straight away and then waits for the requests in flight
+		if err := srv.Shutdown(ctx); err != nil {
+			shutdownError <- err
+			return
+		}
+
+		app.logger.Info("requests finished; stopping background tasks")
+
+		close(app.done)
+
+		waited := make(chan struct{})
+		go func() {
+			app.wg.Wait()
+			close(waited)
+		}()
+
+		select {
+		case <-waited:
+			app.logger.Info("background tasks finished")
+			shutdownError <- nil
+		case <-ctx.Done():
+			shutdownError <- fmt.Errorf("waiting for background tasks: %w", ctx.Err())
+		}
+	}()
+
+	app.logger.Info("starting server", "addr", addr, "env", app.config.env)
+
+	err = srv.ListenAndServe()
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	if err := <-shutdownError; err != nil {
+		return err
+	}
+
+	app.logger.Info("stopped server", "addr", addr)
+
+	return nil
+}
+
+// serveDebug serves /debug/vars on -debug-addr, which is a loopback address so only the machine the api runs on can read it
+// it is on by default at localhost:4001; it returns a function that closes the debug server, and nothing is served when -debug-addr is empty
+func (app *application) serveDebug() (stop func(), err error) {
+	if app.config.debugAddr == "" {
+		return func() {}, nil
+	}
+
+	ln, err := net.Listen("tcp", app.config.debugAddr)
+	if err != nil {
+		return nil, fmt.Errorf("debug server: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /debug/vars", expvarHandler())
+
+	srv := &http.Server{
+		Handler:      mux,
+		ErrorLog:     slog.NewLogLogger(app.logger.Handler(), slog.LevelError),
+		ReadTimeout:  app.config.server.readTimeout,
+		WriteTimeout: app.config.server.writeTimeout,
+	}
+
+	app.logger.Info("starting debug server", "addr", ln.Addr().String())
+
+	go func() {
+		if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+			app.logger.Error("debug server stopped", "error", err)
+		}
+	}()
+
+	//the values are only read, so there is nothing in flight worth waiting for
+	return func() { srv.Close() }, nil
+}
+
+// background runs fn in a goroutine that serve waits for when the server shuts down
+// fn should return once app.done is closed, and a panic in fn is logged instead of taking the whole server down
+func (app *application) background(fn func()) {
+	app.wg.Add(1)
+
+	go func() {
+		defer app.wg.Done()
+
+		defer func() {
+			if err := recover(); err != nil {
+				app.logger.Error("background task panicked", "error", fmt.Sprint(err))
+			}
+		}()
+
+		fn()
+	}()
+}
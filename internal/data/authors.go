@@ -0,0 +1,316 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"readinglist/internal/validator"
+)
+
+// Author is a person who wrote, translated or edited one or more books
+type Author struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"-"`
+	Name      string    `json:"name"`
+	Version   int32     `json:"-"`
+	UserID    int64     `json:"-"` //the user who added the author; nobody else can see or change them
+}
+
+// BookAuthor is an author as they appear on a book, along with the part they played in it
+// when a book is created or updated only the id and role need to be sent; the name is filled in when the book is read
+type BookAuthor struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name,omitempty"`
+	Role string `json:"role"`
+}
+
+// AuthorRoles are the parts an author can play in a book; the database has the same list in a CHECK constraint
+var AuthorRoles = []string{"author", "translator", "editor"}
+
+func ValidateAuthor(v *validator.Validator, author *Author) {
+	v.Check(author.Name != "", "name", "must be provided")
+	v.Check(len(author.Name) <= 500, "name", "must not be more than 500 bytes long")
+}
+
+// ValidateBookAuthors checks the list of authors sent with a book
+func ValidateBookAuthors(v *validator.Validator, authors []BookAuthor) {
+	seen := make(map[BookAuthor]bool)
+
+	for _, author := range authors {
+		v.Check(author.ID > 0, "authors", "must only contain positive author ids")
+		v.Check(validator.PermittedValue(author.Role, AuthorRoles...), "authors", "role must be author, translator or editor")
+
+		key := BookAuthor{ID: author.ID, Role: author.Role}
+		v.Check(!seen[key], "authors", "must not list the same author in the same role twice")
+		seen[key] = true
+	}
+}
+
+// AuthorModel stores authors and the links between authors and books in PostgreSQL
+type AuthorModel struct {
+	DB *sql.DB
+}
+
+func (a AuthorModel) Insert(ctx context.Context, author *Author) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	query := `
+	INSERT INTO authors (name, user_id)
+	VALUES ($1, $2)
+	RETURNING id, created_at, version`
+
+	return a.DB.QueryRowContext(ctx, query, author.Name, author.UserID).Scan(&author.ID, &author.CreatedAt, &author.Version)
+}
+
+func (a AuthorModel) Get(ctx context.Context, id int64, userID int64) (*Author, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+	SELECT id, created_at, name, version, user_id
+	FROM authors
+	WHERE id = $1 AND user_id = $2`
+
+	var author Author
+
+	err := a.DB.QueryRowContext(ctx, query, id, userID).Scan(&author.ID, &author.CreatedAt, &author.Name, &author.Version, &author.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &author, nil
+}
+
+func (a AuthorModel) Update(ctx context.Context, author *Author) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	query := `
+	UPDATE authors
+	SET name = $1, version = version + 1
+	WHERE id = $2 AND version = $3 AND user_id = $4
+	RETURNING version`
+
+	err := a.DB.QueryRowContext(ctx, query, author.Name, author.ID, author.Version, author.UserID).Scan(&author.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes one of userID's authors; the links to their books are removed by the ON DELETE CASCADE on book_authors
+func (a AuthorModel) Delete(ctx context.Context, id int64, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	results, err := a.DB.ExecContext(ctx, `DELETE FROM authors WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := results.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetAll returns one page of userID's authors whose names contain the name filter
+func (a AuthorModel) GetAll(ctx context.Context, userID int64, name string, filters Filters) ([]*Author, Metadata, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), id, created_at, name, version, user_id
+	FROM authors
+	WHERE (name ILIKE '%%' || $1 || '%%' OR $1 = '')
+	AND user_id = $2
+	ORDER BY %s %s, id ASC
+	LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+
+	rows, err := a.DB.QueryContext(ctx, query, name, userID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	authors := []*Author{}
+
+	for rows.Next() {
+		var author Author
+
+		if err := rows.Scan(&totalRecords, &author.ID, &author.CreatedAt, &author.Name, &author.Version, &author.UserID); err != nil {
+			return nil, Metadata{}, err
+		}
+
+		authors = append(authors, &author)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return authors, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// GetBooks returns one page of userID's books that an author worked on in any role
+// the subquery stops a book showing up twice when the author had two roles on it
+func (a AuthorModel) GetBooks(ctx context.Context, authorID int64, userID int64, filters Filters) ([]*Book, Metadata, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), id, created_at, title, published, pages, genres, rating, version, status, current_page, started_at, finished_at, user_id
+	FROM books
+	WHERE id IN (SELECT book_id FROM book_authors WHERE author_id = $1)
+	AND user_id = $2
+	ORDER BY %s %s, id ASC
+	LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+
+	rows, err := a.DB.QueryContext(ctx, query, authorID, userID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	books := []*Book{}
+
+	for rows.Next() {
+		var book Book
+
+		err := rows.Scan(
+			&totalRecords,
+			&book.ID,
+			&book.CreatedAt,
+			&book.Title,
+			&book.Published,
+			&book.Pages,
+			pq.Array(&book.Genres),
+			&book.Rating,
+			&book.Version,
+			&book.Status,
+			&book.CurrentPage,
+			&book.StartedAt,
+			&book.FinishedAt,
+			&book.UserID,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		books = append(books, &book)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return books, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// SetForBook replaces the authors linked to a book
+// the old links are deleted and the new ones inserted in one transaction so a failure leaves the old list in place
+func (a AuthorModel) SetForBook(ctx context.Context, bookID int64, authors []BookAuthor) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	tx, err := a.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM book_authors WHERE book_id = $1`, bookID); err != nil {
+		return err
+	}
+
+	for _, author := range authors {
+		_, err := tx.ExecContext(ctx, `INSERT INTO book_authors (book_id, author_id, role) VALUES ($1, $2, $3)`, bookID, author.ID, author.Role)
+		if err != nil {
+			//23503 is foreign_key_violation, which means the author (or the book) doesn't exist
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+				return ErrRecordNotFound
+			}
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// LoadForBooks fills in the Authors field of each book with one query for the whole list
+func (a AuthorModel) LoadForBooks(ctx context.Context, books []*Book) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	if len(books) == 0 {
+		return nil
+	}
+
+	ids := []int64{}
+	byID := make(map[int64]*Book)
+	for _, book := range books {
+		ids = append(ids, book.ID)
+		byID[book.ID] = book
+		book.Authors = []BookAuthor{}
+	}
+
+	query := `
+	SELECT ba.book_id, a.id, a.name, ba.role
+	FROM book_authors ba
+	JOIN authors a ON a.id = ba.author_id
+	WHERE ba.book_id = ANY($1)
+	ORDER BY ba.book_id, ba.role, a.name`
+
+	rows, err := a.DB.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bookID int64
+		var author BookAuthor
+
+		if err := rows.Scan(&bookID, &author.ID, &author.Name, &author.Role); err != nil {
+			return err
+		}
+
+		byID[bookID].Authors = append(byID[bookID].Authors, author)
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,211 @@
+package data
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryAuthorModel keeps authors and their links to books in memory
+// it needs the book store so it can return an author's books and check that a book exists before linking it
+type MemoryAuthorModel struct {
+	mu      sync.Mutex
+	books   *MemoryBookModel
+	authors map[int64]Author
+	links   map[int64][]BookAuthor //keyed by book id; only the id and role of each author are stored
+	nextID  int64
+}
+
+// NewMemoryAuthorModel returns an empty in-memory author store that links to the books in books
+func NewMemoryAuthorModel(books *MemoryBookModel) *MemoryAuthorModel {
+	return &MemoryAuthorModel{
+		books:   books,
+		authors: make(map[int64]Author),
+		links:   make(map[int64][]BookAuthor),
+		nextID:  1,
+	}
+}
+
+func (m *MemoryAuthorModel) Insert(ctx context.Context, author *Author) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	author.ID = m.nextID
+	author.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	author.Version = 1
+	m.nextID++
+
+	m.authors[author.ID] = *author
+	return nil
+}
+
+func (m *MemoryAuthorModel) Get(ctx context.Context, id int64, userID int64) (*Author, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	author, ok := m.authors[id]
+	if !ok || author.UserID != userID {
+		return nil, ErrRecordNotFound
+	}
+
+	return &author, nil
+}
+
+func (m *MemoryAuthorModel) Update(ctx context.Context, author *Author) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.authors[author.ID]
+	if !ok || stored.Version != author.Version || stored.UserID != author.UserID {
+		return ErrEditConflict
+	}
+
+	author.Version++
+	m.authors[author.ID] = *author
+	return nil
+}
+
+// Delete removes the author and every link to them, like the ON DELETE CASCADE in the SQL versions
+func (m *MemoryAuthorModel) Delete(ctx context.Context, id int64, userID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if author, ok := m.authors[id]; !ok || author.UserID != userID {
+		return ErrRecordNotFound
+	}
+
+	delete(m.authors, id)
+
+	for bookID, links := range m.links {
+		kept := []BookAuthor{}
+		for _, link := range links {
+			if link.ID != id {
+				kept = append(kept, link)
+			}
+		}
+		m.links[bookID] = kept
+	}
+
+	return nil
+}
+
+func (m *MemoryAuthorModel) GetAll(ctx context.Context, userID int64, name string, filters Filters) ([]*Author, Metadata, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	matches := []*Author{}
+	for _, author := range m.authors {
+		if author.UserID != userID {
+			continue
+		}
+		if name != "" && !strings.Contains(strings.ToLower(author.Name), strings.ToLower(name)) {
+			continue
+		}
+		author := author
+		matches = append(matches, &author)
+	}
+
+	descending := filters.sortDirection() == "DESC"
+	byName := filters.sortColumn() == "name"
+
+	sort.Slice(matches, func(i, j int) bool {
+		if byName && matches[i].Name != matches[j].Name {
+			return (matches[i].Name < matches[j].Name) != descending
+		}
+		if !byName && matches[i].ID != matches[j].ID {
+			return (matches[i].ID < matches[j].ID) != descending
+		}
+		return matches[i].ID < matches[j].ID
+	})
+
+	total := len(matches)
+
+	start := filters.offset()
+	if start > total {
+		start = total
+	}
+	end := start + filters.limit()
+	if end > total {
+		end = total
+	}
+
+	return matches[start:end], calculateMetadata(total, filters.Page, filters.PageSize), nil
+}
+
+func (m *MemoryAuthorModel) GetBooks(ctx context.Context, authorID int64, userID int64, filters Filters) ([]*Book, Metadata, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.books.mu.Lock()
+	defer m.books.mu.Unlock()
+
+	matches := []*Book{}
+	for bookID, links := range m.links {
+		book, ok := m.books.books[bookID]
+		if !ok || book.UserID != userID {
+			continue //the book has been deleted since it was linked, or it belongs to someone else
+		}
+
+		for _, link := range links {
+			if link.ID == authorID {
+				matches = append(matches, copyBook(book))
+				break
+			}
+		}
+	}
+
+	sortBooks(matches, filters)
+
+	return paginate(matches, filters), calculateMetadata(len(matches), filters.Page, filters.PageSize), nil
+}
+
+// SetForBook replaces the authors linked to a book
+// ErrRecordNotFound is returned if the book or any of the authors don't exist, like a foreign key error in the SQL versions
+func (m *MemoryAuthorModel) SetForBook(ctx context.Context, bookID int64, authors []BookAuthor) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.books.exists(bookID) {
+		return ErrRecordNotFound
+	}
+
+	links := []BookAuthor{}
+	for _, author := range authors {
+		if _, ok := m.authors[author.ID]; !ok {
+			return ErrRecordNotFound
+		}
+		links = append(links, BookAuthor{ID: author.ID, Role: author.Role})
+	}
+
+	m.links[bookID] = links
+	return nil
+}
+
+// LoadForBooks fills in the Authors field of each book, sorted by role and then name like the SQL versions
+func (m *MemoryAuthorModel) LoadForBooks(ctx context.Context, books []*Book) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, book := range books {
+		book.Authors = []BookAuthor{}
+
+		for _, link := range m.links[book.ID] {
+			author, ok := m.authors[link.ID]
+			if !ok {
+				continue
+			}
+			book.Authors = append(book.Authors, BookAuthor{ID: author.ID, Name: author.Name, Role: link.Role})
+		}
+
+		sort.Slice(book.Authors, func(i, j int) bool {
+			if book.Authors[i].Role != book.Authors[j].Role {
+				return book.Authors[i].Role < book.Authors[j].Role
+			}
+			return book.Authors[i].Name < book.Authors[j].Name
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,229 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SQLiteAuthorModel stores authors and the links between authors and books in SQLite
+// the database has to be opened with foreign keys turned on (_foreign_keys=on) for the ON DELETE CASCADE to work
+type SQLiteAuthorModel struct {
+	DB *sql.DB
+}
+
+func (a SQLiteAuthorModel) Insert(ctx context.Context, author *Author) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	author.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	author.Version = 1
+
+	result, err := a.DB.ExecContext(ctx, `INSERT INTO authors (created_at, name, version, user_id) VALUES (?, ?, ?, ?)`, author.CreatedAt, author.Name, author.Version, author.UserID)
+	if err != nil {
+		return err
+	}
+
+	author.ID, err = result.LastInsertId()
+	return err
+}
+
+func (a SQLiteAuthorModel) Get(ctx context.Context, id int64, userID int64) (*Author, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	var author Author
+
+	query := `SELECT id, created_at, name, version, user_id FROM authors WHERE id = ? AND user_id = ?`
+
+	err := a.DB.QueryRowContext(ctx, query, id, userID).Scan(&author.ID, &author.CreatedAt, &author.Name, &author.Version, &author.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &author, nil
+}
+
+func (a SQLiteAuthorModel) Update(ctx context.Context, author *Author) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	query := `
+	UPDATE authors
+	SET name = ?, version = version + 1
+	WHERE id = ? AND version = ? AND user_id = ?
+	RETURNING version`
+
+	err := a.DB.QueryRowContext(ctx, query, author.Name, author.ID, author.Version, author.UserID).Scan(&author.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrEditConflict
+	}
+	return err
+}
+
+func (a SQLiteAuthorModel) Delete(ctx context.Context, id int64, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	results, err := a.DB.ExecContext(ctx, `DELETE FROM authors WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := results.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (a SQLiteAuthorModel) GetAll(ctx context.Context, userID int64, name string, filters Filters) ([]*Author, Metadata, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), id, created_at, name, version, user_id
+	FROM authors
+	WHERE (name LIKE '%%' || ?1 || '%%' OR ?1 = '')
+	AND user_id = ?2
+	ORDER BY %s %s, id ASC
+	LIMIT ?3 OFFSET ?4`, filters.sortColumn(), filters.sortDirection())
+
+	rows, err := a.DB.QueryContext(ctx, query, name, userID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	authors := []*Author{}
+
+	for rows.Next() {
+		var author Author
+
+		if err := rows.Scan(&totalRecords, &author.ID, &author.CreatedAt, &author.Name, &author.Version, &author.UserID); err != nil {
+			return nil, Metadata{}, err
+		}
+
+		authors = append(authors, &author)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return authors, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+func (a SQLiteAuthorModel) GetBooks(ctx context.Context, authorID int64, userID int64, filters Filters) ([]*Book, Metadata, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), id, created_at, title, published, pages, genres, rating, version, status, current_page, started_at, finished_at, user_id
+	FROM books
+	WHERE id IN (SELECT book_id FROM book_authors WHERE author_id = ?)
+	AND user_id = ?
+	ORDER BY %s %s, id ASC
+	LIMIT ? OFFSET ?`, filters.sortColumn(), filters.sortDirection())
+
+	return SQLiteBookModel{DB: a.DB}.list(ctx, query, filters, authorID, userID, filters.limit(), filters.offset())
+}
+
+func (a SQLiteAuthorModel) SetForBook(ctx context.Context, bookID int64, authors []BookAuthor) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	tx, err := a.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM book_authors WHERE book_id = ?`, bookID); err != nil {
+		return err
+	}
+
+	for _, author := range authors {
+		_, err := tx.ExecContext(ctx, `INSERT INTO book_authors (book_id, author_id, role) VALUES (?, ?, ?)`, bookID, author.ID, author.Role)
+		if err != nil {
+			if strings.Contains(err.Error(), "FOREIGN KEY constraint failed") {
+				return ErrRecordNotFound
+			}
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// LoadForBooks passes the book ids in as a JSON array so the whole list can be loaded with one query
+func (a SQLiteAuthorModel) LoadForBooks(ctx context.Context, books []*Book) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	if len(books) == 0 {
+		return nil
+	}
+
+	ids := []int64{}
+	byID := make(map[int64]*Book)
+	for _, book := range books {
+		ids = append(ids, book.ID)
+		byID[book.ID] = book
+		book.Authors = []BookAuthor{}
+	}
+
+	js, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	SELECT ba.book_id, a.id, a.name, ba.role
+	FROM book_authors ba
+	JOIN authors a ON a.id = ba.author_id
+	WHERE ba.book_id IN (SELECT value FROM json_each(?))
+	ORDER BY ba.book_id, ba.role, a.name`
+
+	rows, err := a.DB.QueryContext(ctx, query, string(js))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bookID int64
+		var author BookAuthor
+
+		if err := rows.Scan(&bookID, &author.ID, &author.Name, &author.Role); err != nil {
+			return err
+		}
+
+		byID[bookID].Authors = append(byID[bookID].Authors, author)
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,218 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMemoryAuthorStore(t *testing.T) {
+	testAuthorStore(t, newMemoryModels)
+}
+
+func TestSQLiteAuthorStore(t *testing.T) {
+	testAuthorStore(t, newSQLiteModels)
+}
+
+func TestPostgresAuthorStore(t *testing.T) {
+	testAuthorStore(t, newPostgresModels)
+}
+
+func testAuthorStore(t *testing.T, newModels func(t *testing.T) Models) {
+	authorFilters := Filters{Page: 1, PageSize: 20, Sort: "name", SortSafelist: []string{"id", "name", "-id", "-name"}}
+	bookFilters := Filters{Page: 1, PageSize: 20, Sort: "id", SortSafelist: sortSafelist}
+
+	t.Run("CRUD", func(t *testing.T) {
+		models := newModels(t)
+		user := seedUser(t, models.Users, "reader@example.com")
+
+		author := &Author{Name: "Ursula K. Le Guin", UserID: user.ID}
+		if err := models.Authors.Insert(context.Background(), author); err != nil {
+			t.Fatal(err)
+		}
+
+		if author.ID < 1 || author.Version != 1 {
+			t.Fatalf("Insert did not set id and version: %+v", author)
+		}
+
+		author.Name = "Ursula Le Guin"
+		if err := models.Authors.Update(context.Background(), author); err != nil {
+			t.Fatal(err)
+		}
+
+		stale := *author
+		stale.Version = 1
+		if err := models.Authors.Update(context.Background(), &stale); !errors.Is(err, ErrEditConflict) {
+			t.Errorf("Update with a stale version returned %v; want ErrEditConflict", err)
+		}
+
+		got, err := models.Authors.Get(context.Background(), author.ID, user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got.Name != "Ursula Le Guin" || got.Version != 2 || got.UserID != user.ID {
+			t.Errorf("Get after update = %+v", got)
+		}
+
+		if err := models.Authors.Delete(context.Background(), author.ID, user.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := models.Authors.Get(context.Background(), author.ID, user.ID); !errors.Is(err, ErrRecordNotFound) {
+			t.Errorf("Get after Delete returned %v; want ErrRecordNotFound", err)
+		}
+	})
+
+	t.Run("GetAll", func(t *testing.T) {
+		models := newModels(t)
+		user := seedUser(t, models.Users, "reader@example.com")
+		other := seedUser(t, models.Users, "other@example.com")
+
+		for _, name := range []string{"Frank Herbert", "J. R. R. Tolkien", "William Gibson"} {
+			if err := models.Authors.Insert(context.Background(), &Author{Name: name, UserID: user.ID}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		//another user's authors are never listed
+		if err := models.Authors.Insert(context.Background(), &Author{Name: "Tolkien, J. R. R.", UserID: other.ID}); err != nil {
+			t.Fatal(err)
+		}
+
+		authors, metadata, err := models.Authors.GetAll(context.Background(), user.ID, "", Filters{Page: 1, PageSize: 20, Sort: "-name", SortSafelist: authorFilters.SortSafelist})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(authors) != 3 || authors[0].Name != "William Gibson" || metadata.TotalRecords != 3 {
+			t.Errorf("GetAll returned %d authors starting with %+v, metadata %+v", len(authors), authors[0], metadata)
+		}
+
+		authors, _, err = models.Authors.GetAll(context.Background(), user.ID, "tolk", authorFilters)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(authors) != 1 || authors[0].Name != "J. R. R. Tolkien" {
+			t.Errorf("GetAll with a name filter returned %d authors", len(authors))
+		}
+	})
+
+	t.Run("BookLinks", func(t *testing.T) {
+		models := newModels(t)
+		user := seedUser(t, models.Users, "reader@example.com")
+		books := seedBooks(t, models.Books, user.ID)
+
+		tolkien := &Author{Name: "J. R. R. Tolkien", UserID: user.ID}
+		christopher := &Author{Name: "Christopher Tolkien", UserID: user.ID}
+		for _, author := range []*Author{tolkien, christopher} {
+			if err := models.Authors.Insert(context.Background(), author); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		err := models.Authors.SetForBook(context.Background(), books[0].ID, []BookAuthor{{ID: tolkien.ID, Role: "author"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = models.Authors.SetForBook(context.Background(), books[2].ID, []BookAuthor{
+			{ID: tolkien.ID, Role: "author"},
+			{ID: christopher.ID, Role: "editor"},
+			{ID: tolkien.ID, Role: "editor"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		//linking an author that doesn't exist fails
+		if err := models.Authors.SetForBook(context.Background(), books[1].ID, []BookAuthor{{ID: 999, Role: "author"}}); !errors.Is(err, ErrRecordNotFound) {
+			t.Errorf("SetForBook with a missing author returned %v; want ErrRecordNotFound", err)
+		}
+
+		got, metadata, err := models.Authors.GetBooks(context.Background(), tolkien.ID, user.ID, bookFilters)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if want := []string{"The Hobbit", "The Lord of the Rings"}; !equalStrings(titles(got), want) || metadata.TotalRecords != 2 {
+			t.Errorf("GetBooks = %q (%d records); want %q", titles(got), metadata.TotalRecords, want)
+		}
+
+		if err := models.Authors.LoadForBooks(context.Background(), books); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(books[1].Authors) != 0 {
+			t.Errorf("book with no authors got %+v", books[1].Authors)
+		}
+
+		want := []BookAuthor{
+			{ID: tolkien.ID, Name: "J. R. R. Tolkien", Role: "author"},
+			{ID: christopher.ID, Name: "Christopher Tolkien", Role: "editor"},
+			{ID: tolkien.ID, Name: "J. R. R. Tolkien", Role: "editor"},
+		}
+		if len(books[2].Authors) != len(want) {
+			t.Fatalf("LoadForBooks = %+v; want %+v", books[2].Authors, want)
+		}
+		for i := range want {
+			if books[2].Authors[i] != want[i] {
+				t.Errorf("author %d = %+v; want %+v", i, books[2].Authors[i], want[i])
+			}
+		}
+
+		//deleting an author removes them from the books they were linked to
+		if err := models.Authors.Delete(context.Background(), christopher.ID, user.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := models.Authors.LoadForBooks(context.Background(), books[2:3]); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(books[2].Authors) != 2 {
+			t.Errorf("authors after delete = %+v", books[2].Authors)
+		}
+	})
+	t.Run("Ownership", func(t *testing.T) {
+		models := newModels(t)
+		owner := seedUser(t, models.Users, "reader@example.com")
+		other := seedUser(t, models.Users, "other@example.com")
+		books := seedBooks(t, models.Books, owner.ID)
+
+		author := &Author{Name: "Frank Herbert", UserID: owner.ID}
+		if err := models.Authors.Insert(context.Background(), author); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := models.Authors.SetForBook(context.Background(), books[0].ID, []BookAuthor{{ID: author.ID, Role: "author"}}); err != nil {
+			t.Fatal(err)
+		}
+
+		//to anyone but the owner the author doesn't exist, so they can't read, rename or delete them
+		if _, err := models.Authors.Get(context.Background(), author.ID, other.ID); !errors.Is(err, ErrRecordNotFound) {
+			t.Errorf("Get by another user returned %v; want ErrRecordNotFound", err)
+		}
+
+		renamed := *author
+		renamed.Name = "Someone Else"
+		renamed.UserID = other.ID
+		if err := models.Authors.Update(context.Background(), &renamed); !errors.Is(err, ErrEditConflict) {
+			t.Errorf("Update by another user returned %v; want ErrEditConflict", err)
+		}
+
+		if err := models.Authors.Delete(context.Background(), author.ID, other.ID); !errors.Is(err, ErrRecordNotFound) {
+			t.Errorf("Delete by another user returned %v; want ErrRecordNotFound", err)
+		}
+
+		//the owner's book still has its author, with the name unchanged
+		if err := models.Authors.LoadForBooks(context.Background(), books[:1]); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(books[0].Authors) != 1 || books[0].Authors[0].Name != "Frank Herbert" {
+			t.Errorf("authors on the owner's book = %+v; want Frank Herbert", books[0].Authors)
+		}
+	})
+}
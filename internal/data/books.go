@@ -4,11 +4,15 @@ package data
 //It means that any package under internal cannot be imported from outside this project
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/lib/pq"
+
+	"readinglist/internal/validator"
 )
 
 // below is a struct that will be used to type a group of related data
@@ -22,8 +26,45 @@ type Book struct {
 	Published int      `json:"published,omitempty"` //this json tag makes this field optional
 	Pages     int      `json:"pages,omitempty"`
 	Genres    []string `json:"genres,omitempty"`
-	Rating    float32  `json:"rating,omitempty"`
+	Rating    float32  `json:"rating"` //0 is a real rating and PUT needs every field, so this is always sent
 	Version   int32    `json:"-"`
+	UserID    int64    `json:"-"` //the user the book belongs to; every user has their own reading list
+	//the reading status and progress; see progress.go for the statuses and the rules for moving between them
+	Status      string     `json:"status"`
+	CurrentPage int        `json:"current_page"`
+	StartedAt   *time.Time `json:"started_at,omitempty"` //pointers because a book that hasn't been started or finished has no date
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	//the authors are stored in their own table, so they are only filled in when the handler asks the AuthorStore for them
+	//LoadForBooks makes the list empty rather than nil, so a book without authors is sent with "authors": [] that can go straight back in a PUT
+	Authors []BookAuthor `json:"authors"`
+}
+
+// ValidateBook checks the fields a client can set on a book and adds a message to v for each one that is wrong
+func ValidateBook(v *validator.Validator, book *Book) {
+	v.Check(book.Title != "", "title", "must be provided")
+	v.Check(len(book.Title) <= 500, "title", "must not be more than 500 bytes long")
+
+	v.Check(book.Published != 0, "published", "must be provided")
+	v.Check(book.Published >= 1, "published", "must be a positive year")
+	v.Check(book.Published <= time.Now().Year(), "published", "must not be in the future")
+
+	v.Check(book.Pages != 0, "pages", "must be provided")
+	v.Check(book.Pages > 0, "pages", "must be a positive integer")
+
+	v.Check(book.Genres != nil, "genres", "must be provided")
+	v.Check(len(book.Genres) >= 1, "genres", "must contain at least 1 genre")
+	v.Check(len(book.Genres) <= 5, "genres", "must not contain more than 5 genres")
+	v.Check(validator.Unique(book.Genres), "genres", "must not contain duplicate values")
+
+	v.Check(book.Rating >= 0, "rating", "must not be less than 0")
+	v.Check(book.Rating <= 5, "rating", "must not be more than 5")
+
+	v.Check(validator.PermittedValue(book.Status, ReadingStatuses...), "status", "must be want_to_read, reading, finished or abandoned")
+	v.Check(book.CurrentPage >= 0, "current_page", "must not be negative")
+	//comparing against pages only means something when pages is valid, otherwise the client would get a second error for the same mistake
+	if book.Pages > 0 {
+		v.Check(book.CurrentPage <= book.Pages, "current_page", "must not be more than the number of pages")
+	}
 }
 
 // this type is connected to all of the methods that implement the crud operations
@@ -33,39 +74,46 @@ type BookModel struct {
 
 // this method "hangs off of" the BookModel type - like all of the following methods
 // it takes in a pointer to a book - that is a pointer to a book record that is coming in to the database
-func (b BookModel) Insert(book *Book) error {
+func (b BookModel) Insert(ctx context.Context, book *Book) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
 	//the query variable holds the postgres sql statement that will be run to create a new record
 	//the values are "positional arguments" and are being populated by the args variable below
 	query := `
-	INSERT INTO books (title, published, pages, genres, rating)
-	VALUES ($1, $2, $3, $4, $5)
+	INSERT INTO books (title, published, pages, genres, rating, status, current_page, started_at, finished_at, user_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 	RETURNING id, created_at, version`
 
 	//the blank interface below is taking in all the information from the pointer to a book above and then populates the query variable VALUES
-	args := []interface{}{book.Title, book.Published, book.Pages, pq.Array(book.Genres), book.Rating}
+	args := []interface{}{book.Title, book.Published, book.Pages, pq.Array(book.Genres), book.Rating, book.Status, book.CurrentPage, book.StartedAt, book.FinishedAt, book.UserID}
 
 	//this first runs the INSERT statement with the query and the args so the row is put into the database
 	//it then returns back some values with the second part (which corresponds to the RETURNING part of the statement above)
 	//the Scan part returns dereferenced pointers to those aspects of the book object because these are system generated
-	return b.DB.QueryRow(query, args...).Scan(&book.ID, &book.CreatedAt, &book.Version) //returns the dereferenced pointer, auto-generated values to Go object
+	return b.DB.QueryRowContext(ctx, query, args...).Scan(&book.ID, &book.CreatedAt, &book.Version) //returns the dereferenced pointer, auto-generated values to Go object
 }
 
 // this method takes in a book id and returns a pointer to a book and an error
-func (b BookModel) Get(id int64) (*Book, error) {
+// only the books that belong to userID can be found; anyone else's book is reported as not found
+func (b BookModel) Get(ctx context.Context, id int64, userID int64) (*Book, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
 	//this returns an error if the id is invalid
 	if id < 1 {
-		return nil, errors.New("record not found")
+		return nil, ErrRecordNotFound
 	}
 	//this pulls the specific record from the database
 	query := `
-	SELECT id, created_at, title, published, pages, genres, rating, version
+	SELECT id, created_at, title, published, pages, genres, rating, version, status, current_page, started_at, finished_at, user_id
 	FROM books
-	WHERE id = $1`
+	WHERE id = $1 AND user_id = $2`
 	//this variable is used to hold all of the information for the book record from the database
 	var book Book
 	//Below passes back the scanned information
 	//Scan is taking in the query and id information and then populating the variable with the record returned from the database
-	err := b.DB.QueryRow(query, id).Scan(
+	err := b.DB.QueryRowContext(ctx, query, id, userID).Scan(
 		&book.ID,
 		&book.CreatedAt,
 		&book.Title,
@@ -74,13 +122,18 @@ func (b BookModel) Get(id int64) (*Book, error) {
 		pq.Array(&book.Genres),
 		&book.Rating,
 		&book.Version,
+		&book.Status,
+		&book.CurrentPage,
+		&book.StartedAt,
+		&book.FinishedAt,
+		&book.UserID,
 	)
 	//this switch case is handling potential errors
 	if err != nil {
 		switch {
 		//this case handles when there are no records with the specific id found
 		case errors.Is(err, sql.ErrNoRows):
-			return nil, errors.New("record not found")
+			return nil, ErrRecordNotFound
 		default:
 			return nil, err
 
@@ -89,27 +142,49 @@ func (b BookModel) Get(id int64) (*Book, error) {
 	return &book, nil //this returns the book object with a nil error
 }
 
-func (b BookModel) Update(book *Book) error {
+func (b BookModel) Update(ctx context.Context, book *Book) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
 	query := `
 	UPDATE books
-	SET title = $1, published = $2, pages = $3, genres = $4, rating = $5, version = version +1
-	WHERE id = $6 AND version = $7
+	SET title = $1, published = $2, pages = $3, genres = $4, rating = $5,
+		status = $6, current_page = $7, started_at = $8, finished_at = $9, version = version +1
+	WHERE id = $10 AND user_id = $11 AND version = $12
 	RETURNING version`
 
-	args := []interface{}{book.Title, book.Published, book.Pages, pq.Array(book.Genres), book.Rating, book.ID, book.Version}
-	return b.DB.QueryRow(query, args...).Scan(&book.Version)
+	args := []interface{}{book.Title, book.Published, book.Pages, pq.Array(book.Genres), book.Rating,
+		book.Status, book.CurrentPage, book.StartedAt, book.FinishedAt, book.ID, book.UserID, book.Version}
+
+	//no rows means the version in the database has moved on (or the book was deleted) since the book was read
+	err := b.DB.QueryRowContext(ctx, query, args...).Scan(&book.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
 }
 
-func (b BookModel) Delete(id int64) error {
+// Delete removes one of userID's books
+// when version isn't 0 the book is only deleted if it is still at that version, otherwise ErrEditConflict is returned
+func (b BookModel) Delete(ctx context.Context, id int64, userID int64, version int32) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
 	if id < 1 {
-		return errors.New("record not found")
+		return ErrRecordNotFound
 	}
 
 	query := `
 	DELETE FROM books
-	WHERE id = $1`
+	WHERE id = $1 AND user_id = $2 AND ($3 = 0 OR version = $3)`
 
-	results, err := b.DB.Exec(query, id)
+	results, err := b.DB.ExecContext(ctx, query, id, userID, version)
 	if err != nil {
 		return err
 	}
@@ -121,27 +196,43 @@ func (b BookModel) Delete(id int64) error {
 	}
 	//this returns an error if no rows were affected
 	if rowsAffected == 0 {
-		return errors.New("record not found")
+		if version != 0 {
+			return ErrEditConflict
+		}
+		return ErrRecordNotFound
 	}
 
 	return nil
 }
 
-// GetAll doesn't take anything, but it does return a slice with pointers to books and an error
-func (b BookModel) GetAll() ([]*Book, error) {
-	query := `
-	SELECT *
+// GetAll returns one page of userID's books that match the title and genres filters along with the paging metadata
+// an empty title or an empty genres slice means that filter isn't applied
+func (b BookModel) GetAll(ctx context.Context, userID int64, title string, genres []string, filters Filters) ([]*Book, Metadata, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	//the sort column and direction can't be passed in as positional arguments so they are put into the query with Sprintf
+	//this is safe because sortColumn only ever returns a value from the safelist
+	//count(*) OVER() adds the total number of matching rows (before LIMIT and OFFSET) to every row
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), id, created_at, title, published, pages, genres, rating, version, status, current_page, started_at, finished_at, user_id
 	FROM books
-	ORDER BY id
-	`
+	WHERE user_id = $1
+	AND (title ILIKE '%%' || $2 || '%%' OR $2 = '')
+	AND (genres @> $3 OR $3 = '{}')
+	ORDER BY %s %s, id ASC
+	LIMIT $4 OFFSET $5`, filters.sortColumn(), filters.sortDirection())
+
+	args := []interface{}{userID, title, pq.Array(genres), filters.limit(), filters.offset()}
 
-	rows, err := b.DB.Query(query)
+	rows, err := b.DB.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, Metadata{}, err
 	}
 	//the code below ends the database search when there are no more rows to find
 	defer rows.Close()
 
+	totalRecords := 0
 	books := []*Book{} //this variable is a slice containing books of type Book
 
 	//below convets the database record into an object
@@ -152,6 +243,7 @@ func (b BookModel) GetAll() ([]*Book, error) {
 		var book Book
 
 		err := rows.Scan(
+			&totalRecords,
 			&book.ID,
 			&book.CreatedAt,
 			&book.Title,
@@ -160,9 +252,14 @@ func (b BookModel) GetAll() ([]*Book, error) {
 			pq.Array(&book.Genres),
 			&book.Rating,
 			&book.Version,
+			&book.Status,
+			&book.CurrentPage,
+			&book.StartedAt,
+			&book.FinishedAt,
+			&book.UserID,
 		)
 		if err != nil {
-			return nil, err
+			return nil, Metadata{}, err
 		}
 
 		//the book object is then added to the books variable
@@ -170,7 +267,70 @@ func (b BookModel) GetAll() ([]*Book, error) {
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, err
+		return nil, Metadata{}, err
 	}
-	return books, nil
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return books, metadata, nil
+}
+
+// Search returns one page of userID's books whose titles match the search terms, with the best matches first
+// the terms are turned into a full-text query with plainto_tsquery so "lord rings" finds "The Lord of the Rings"
+// the english configuration reduces words to their stems so "running" also matches "run"
+func (b BookModel) Search(ctx context.Context, userID int64, q string, filters Filters) ([]*Book, Metadata, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	//this expression has to match the one the GIN index is built on in internal/migrate/migrations/postgres/000002_add_books_title_index.up.sql or the index won't be used
+	query := `
+	SELECT count(*) OVER(), id, created_at, title, published, pages, genres, rating, version, status, current_page, started_at, finished_at, user_id
+	FROM books
+	WHERE user_id = $1
+	AND to_tsvector('english', title) @@ plainto_tsquery('english', $2)
+	ORDER BY ts_rank(to_tsvector('english', title), plainto_tsquery('english', $2)) DESC, id ASC
+	LIMIT $3 OFFSET $4`
+
+	rows, err := b.DB.QueryContext(ctx, query, userID, q, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	books := []*Book{}
+
+	for rows.Next() {
+		var book Book
+
+		err := rows.Scan(
+			&totalRecords,
+			&book.ID,
+			&book.CreatedAt,
+			&book.Title,
+			&book.Published,
+			&book.Pages,
+			pq.Array(&book.Genres),
+			&book.Rating,
+			&book.Version,
+			&book.Status,
+			&book.CurrentPage,
+			&book.StartedAt,
+			&book.FinishedAt,
+			&book.UserID,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		books = append(books, &book)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return books, metadata, nil
 }
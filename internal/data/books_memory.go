@@ -0,0 +1,235 @@
+package data
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryBookModel keeps books in a map instead of a database
+// the mutex protects the map because the http server calls the handlers from many goroutines at once
+type MemoryBookModel struct {
+	mu     sync.Mutex
+	books  map[int64]Book
+	nextID int64
+}
+
+// NewMemoryBookModel returns an empty in-memory book store
+func NewMemoryBookModel() *MemoryBookModel {
+	return &MemoryBookModel{
+		books:  make(map[int64]Book),
+		nextID: 1,
+	}
+}
+
+// copyBook returns a copy of the book with its own genres slice
+// this stops callers from changing the stored book through the pointer or the slice they get back
+func copyBook(book Book) *Book {
+	if book.Genres != nil {
+		book.Genres = append([]string{}, book.Genres...)
+	}
+	return &book
+}
+
+func (m *MemoryBookModel) Insert(ctx context.Context, book *Book) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	book.ID = m.nextID
+	book.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	book.Version = 1
+	m.nextID++
+
+	m.books[book.ID] = *copyBook(*book)
+	return nil
+}
+
+func (m *MemoryBookModel) Get(ctx context.Context, id int64, userID int64) (*Book, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	book, ok := m.books[id]
+	if !ok || book.UserID != userID {
+		return nil, ErrRecordNotFound
+	}
+
+	return copyBook(book), nil
+}
+
+// Update only saves the book if nobody else has changed it since it was read, the same as the version check in BookModel.Update
+func (m *MemoryBookModel) Update(ctx context.Context, book *Book) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.books[book.ID]
+	if !ok || stored.UserID != book.UserID || stored.Version != book.Version {
+		return ErrEditConflict
+	}
+
+	book.Version++
+	m.books[book.ID] = *copyBook(*book)
+	return nil
+}
+
+func (m *MemoryBookModel) Delete(ctx context.Context, id int64, userID int64, version int32) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.books[id]
+	ok = ok && stored.UserID == userID
+
+	switch {
+	case !ok && version != 0:
+		return ErrEditConflict
+	case !ok:
+		return ErrRecordNotFound
+	case version != 0 && stored.Version != version:
+		return ErrEditConflict
+	}
+
+	delete(m.books, id)
+	return nil
+}
+
+// GetAll applies the same filters, sorting and paging as BookModel.GetAll
+func (m *MemoryBookModel) GetAll(ctx context.Context, userID int64, title string, genres []string, filters Filters) ([]*Book, Metadata, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	matches := []*Book{}
+	for _, book := range m.books {
+		if book.UserID != userID {
+			continue
+		}
+		if title != "" && !strings.Contains(strings.ToLower(book.Title), strings.ToLower(title)) {
+			continue
+		}
+		if !containsAll(book.Genres, genres) {
+			continue
+		}
+		matches = append(matches, copyBook(book))
+	}
+
+	sortBooks(matches, filters)
+
+	return paginate(matches, filters), calculateMetadata(len(matches), filters.Page, filters.PageSize), nil
+}
+
+// Search finds books whose titles contain every word in q, with shorter titles first like SQLiteBookModel.Search
+func (m *MemoryBookModel) Search(ctx context.Context, userID int64, q string, filters Filters) ([]*Book, Metadata, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	words := strings.Fields(strings.ToLower(q))
+	if len(words) == 0 {
+		return []*Book{}, Metadata{}, nil
+	}
+
+	matches := []*Book{}
+	for _, book := range m.books {
+		if book.UserID != userID {
+			continue
+		}
+
+		title := strings.ToLower(book.Title)
+
+		found := true
+		for _, word := range words {
+			if !strings.Contains(title, word) {
+				found = false
+				break
+			}
+		}
+
+		if found {
+			matches = append(matches, copyBook(book))
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if len(matches[i].Title) != len(matches[j].Title) {
+			return len(matches[i].Title) < len(matches[j].Title)
+		}
+		return matches[i].ID < matches[j].ID
+	})
+
+	return paginate(matches, filters), calculateMetadata(len(matches), filters.Page, filters.PageSize), nil
+}
+
+// exists reports whether there is a book with the id, whoever it belongs to
+// the author and progress stores use it where the SQL versions rely on a foreign key
+func (m *MemoryBookModel) exists(id int64) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.books[id]
+	return ok
+}
+
+// containsAll reports whether every value in want is also in have
+func containsAll(have, want []string) bool {
+	for _, w := range want {
+		found := false
+		for _, h := range have {
+			if h == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// sortBooks orders the books by the sort column in the filters
+// ties are broken by id so the order is always the same, like the ", id ASC" in the SQL versions
+func sortBooks(books []*Book, filters Filters) {
+	less := bookLess(filters.sortColumn())
+	descending := filters.sortDirection() == "DESC"
+
+	sort.Slice(books, func(i, j int) bool {
+		switch {
+		case less(books[i], books[j]):
+			return !descending
+		case less(books[j], books[i]):
+			return descending
+		default:
+			return books[i].ID < books[j].ID
+		}
+	})
+}
+
+// bookLess returns a function that compares two books on one of the sortable columns
+func bookLess(column string) func(a, b *Book) bool {
+	switch column {
+	case "title":
+		return func(a, b *Book) bool { return a.Title < b.Title }
+	case "published":
+		return func(a, b *Book) bool { return a.Published < b.Published }
+	case "pages":
+		return func(a, b *Book) bool { return a.Pages < b.Pages }
+	case "rating":
+		return func(a, b *Book) bool { return a.Rating < b.Rating }
+	default:
+		return func(a, b *Book) bool { return a.ID < b.ID }
+	}
+}
+
+// paginate returns the books on the page the filters ask for
+func paginate(books []*Book, filters Filters) []*Book {
+	start := filters.offset()
+	if start >= len(books) {
+		return []*Book{}
+	}
+
+	end := start + filters.limit()
+	if end > len(books) {
+		end = len(books)
+	}
+
+	return books[start:end]
+}
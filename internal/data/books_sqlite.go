@@ -0,0 +1,259 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// jsonArray lets a []string be written to and read from a JSON text column
+// it does the same job for SQLite that pq.Array does for PostgreSQL
+type jsonArray struct {
+	values *[]string
+}
+
+func (a jsonArray) Value() (driver.Value, error) {
+	if *a.values == nil {
+		return "[]", nil
+	}
+
+	js, err := json.Marshal(*a.values)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(js), nil
+}
+
+func (a jsonArray) Scan(src any) error {
+	switch src := src.(type) {
+	case string:
+		return json.Unmarshal([]byte(src), a.values)
+	case []byte:
+		return json.Unmarshal(src, a.values)
+	case nil:
+		*a.values = nil
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into a JSON array", src)
+	}
+}
+
+// SQLiteBookModel stores books in a SQLite database
+// the SQL is the same as BookModel's wherever the two databases agree
+// SQLite doesn't have array columns, so the genres are kept as a JSON array in a text column (see internal/migrate)
+type SQLiteBookModel struct {
+	DB *sql.DB
+}
+
+func (b SQLiteBookModel) Insert(ctx context.Context, book *Book) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	book.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	book.Version = 1
+
+	query := `
+	INSERT INTO books (created_at, title, published, pages, genres, rating, version, status, current_page, started_at, finished_at, user_id)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	args := []interface{}{book.CreatedAt, book.Title, book.Published, book.Pages, jsonArray{&book.Genres}, book.Rating, book.Version,
+		book.Status, book.CurrentPage, book.StartedAt, book.FinishedAt, book.UserID}
+
+	result, err := b.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	book.ID, err = result.LastInsertId()
+	return err
+}
+
+func (b SQLiteBookModel) Get(ctx context.Context, id int64, userID int64) (*Book, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+	SELECT id, created_at, title, published, pages, genres, rating, version, status, current_page, started_at, finished_at, user_id
+	FROM books
+	WHERE id = ? AND user_id = ?`
+
+	var book Book
+
+	err := b.DB.QueryRowContext(ctx, query, id, userID).Scan(
+		&book.ID,
+		&book.CreatedAt,
+		&book.Title,
+		&book.Published,
+		&book.Pages,
+		jsonArray{&book.Genres},
+		&book.Rating,
+		&book.Version,
+		&book.Status,
+		&book.CurrentPage,
+		&book.StartedAt,
+		&book.FinishedAt,
+		&book.UserID,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &book, nil
+}
+
+func (b SQLiteBookModel) Update(ctx context.Context, book *Book) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	query := `
+	UPDATE books
+	SET title = ?, published = ?, pages = ?, genres = ?, rating = ?,
+		status = ?, current_page = ?, started_at = ?, finished_at = ?, version = version + 1
+	WHERE id = ? AND user_id = ? AND version = ?
+	RETURNING version`
+
+	args := []interface{}{book.Title, book.Published, book.Pages, jsonArray{&book.Genres}, book.Rating,
+		book.Status, book.CurrentPage, book.StartedAt, book.FinishedAt, book.ID, book.UserID, book.Version}
+
+	err := b.DB.QueryRowContext(ctx, query, args...).Scan(&book.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrEditConflict
+	}
+	return err
+}
+
+func (b SQLiteBookModel) Delete(ctx context.Context, id int64, userID int64, version int32) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	results, err := b.DB.ExecContext(ctx, `DELETE FROM books WHERE id = ?1 AND user_id = ?2 AND (?3 = 0 OR version = ?3)`, id, userID, version)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := results.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		if version != 0 {
+			return ErrEditConflict
+		}
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetAll works like BookModel.GetAll
+// the genres filter uses json_each to check that every wanted genre is in the book's JSON array
+func (b SQLiteBookModel) GetAll(ctx context.Context, userID int64, title string, genres []string, filters Filters) ([]*Book, Metadata, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), id, created_at, title, published, pages, genres, rating, version, status, current_page, started_at, finished_at, user_id
+	FROM books
+	WHERE user_id = ?1
+	AND (title LIKE '%%' || ?2 || '%%' OR ?2 = '')
+	AND NOT EXISTS (
+		SELECT 1 FROM json_each(?3) AS wanted
+		WHERE wanted.value NOT IN (SELECT value FROM json_each(books.genres))
+	)
+	ORDER BY %s %s, id ASC
+	LIMIT ?4 OFFSET ?5`, filters.sortColumn(), filters.sortDirection())
+
+	return b.list(ctx, query, filters, userID, title, jsonArray{&genres}, filters.limit(), filters.offset())
+}
+
+// Search finds books whose titles contain every word in q
+// SQLite doesn't have PostgreSQL's text search, so shorter titles (which are closer matches) come first
+func (b SQLiteBookModel) Search(ctx context.Context, userID int64, q string, filters Filters) ([]*Book, Metadata, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	words := strings.Fields(q)
+	if len(words) == 0 {
+		return []*Book{}, Metadata{}, nil
+	}
+
+	args := []interface{}{userID}
+	conditions := []string{"user_id = ?"}
+	for _, word := range words {
+		args = append(args, word)
+		conditions = append(conditions, "title LIKE '%' || ? || '%'")
+	}
+	args = append(args, filters.limit(), filters.offset())
+
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), id, created_at, title, published, pages, genres, rating, version, status, current_page, started_at, finished_at, user_id
+	FROM books
+	WHERE %s
+	ORDER BY length(title) ASC, id ASC
+	LIMIT ? OFFSET ?`, strings.Join(conditions, " AND "))
+
+	return b.list(ctx, query, filters, args...)
+}
+
+// list runs a query that returns the total count followed by the book columns and works out the paging metadata
+func (b SQLiteBookModel) list(ctx context.Context, query string, filters Filters, args ...interface{}) ([]*Book, Metadata, error) {
+	rows, err := b.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	books := []*Book{}
+
+	for rows.Next() {
+		var book Book
+
+		err := rows.Scan(
+			&totalRecords,
+			&book.ID,
+			&book.CreatedAt,
+			&book.Title,
+			&book.Published,
+			&book.Pages,
+			jsonArray{&book.Genres},
+			&book.Rating,
+			&book.Version,
+			&book.Status,
+			&book.CurrentPage,
+			&book.StartedAt,
+			&book.FinishedAt,
+			&book.UserID,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		books = append(books, &book)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return books, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
@@ -0,0 +1,466 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+
+	"readinglist/internal/migrate"
+	"readinglist/internal/validator"
+)
+
+// every store implementation is run through the same conformance tests
+// the PostgreSQL stores are only tested when READINGLIST_TEST_DSN points at a migrated database
+
+func newMemoryModels(t *testing.T) Models {
+	return NewMemoryModels()
+}
+
+func newSQLiteModels(t *testing.T) Models {
+	//foreign keys are off by default in SQLite and the ON DELETE CASCADE rules need them
+	db, err := sql.Open("sqlite3", ":memory:?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	//each connection to :memory: gets its own database, so the pool has to stay on one connection
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := migrate.New(db, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	return NewSQLiteModels(db)
+}
+
+func newPostgresModels(t *testing.T) Models {
+	dsn := os.Getenv("READINGLIST_TEST_DSN")
+	if dsn == "" {
+		t.Skip("READINGLIST_TEST_DSN not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec("TRUNCATE books, authors, users RESTART IDENTITY CASCADE"); err != nil {
+		t.Fatal(err)
+	}
+
+	return NewModels(db)
+}
+
+func TestMemoryBookStore(t *testing.T) {
+	testBookStore(t, newMemoryModels)
+}
+
+func TestSQLiteBookStore(t *testing.T) {
+	testBookStore(t, newSQLiteModels)
+}
+
+func TestPostgresBookStore(t *testing.T) {
+	testBookStore(t, newPostgresModels)
+}
+
+var sortSafelist = []string{"id", "title", "published", "pages", "rating", "-id", "-title", "-published", "-pages", "-rating"}
+
+// seedUser inserts a user to own the books in a test
+// the hash isn't a real bcrypt hash because hashing a password is slow and most tests never log in
+func seedUser(t *testing.T, users UserStore, email string) *User {
+	t.Helper()
+
+	user := &User{Name: "Test User", Email: email}
+	user.Password.hash = []byte("not a real hash")
+
+	if err := users.Insert(context.Background(), user); err != nil {
+		t.Fatalf("Insert(%q): %v", email, err)
+	}
+
+	return user
+}
+
+// seedBooks inserts a small set of books for the user and returns them in insert order
+func seedBooks(t *testing.T, store BookStore, userID int64) []*Book {
+	t.Helper()
+
+	books := []*Book{
+		{Title: "The Hobbit", Published: 1937, Pages: 310, Genres: []string{"fantasy", "adventure"}, Rating: 4.7, Status: StatusWantToRead},
+		{Title: "Dune", Published: 1965, Pages: 412, Genres: []string{"science fiction"}, Rating: 4.5, Status: StatusWantToRead},
+		{Title: "The Lord of the Rings", Published: 1954, Pages: 1178, Genres: []string{"fantasy"}, Rating: 4.9, Status: StatusWantToRead},
+		{Title: "Neuromancer", Published: 1984, Pages: 271, Genres: []string{"science fiction", "cyberpunk"}, Rating: 3.9, Status: StatusWantToRead},
+	}
+
+	for _, book := range books {
+		book.UserID = userID
+		if err := store.Insert(context.Background(), book); err != nil {
+			t.Fatalf("Insert(%q): %v", book.Title, err)
+		}
+	}
+
+	return books
+}
+
+func titles(books []*Book) []string {
+	out := []string{}
+	for _, book := range books {
+		out = append(out, book.Title)
+	}
+	return out
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func testBookStore(t *testing.T, newModels func(t *testing.T) Models) {
+	//most of the tests only need the book store and the id of a user to own the books
+	newStore := func(t *testing.T) (BookStore, int64) {
+		models := newModels(t)
+		return models.Books, seedUser(t, models.Users, "reader@example.com").ID
+	}
+
+	t.Run("InsertAndGet", func(t *testing.T) {
+		store, userID := newStore(t)
+
+		book := &Book{Title: "Dune", Published: 1965, Pages: 412, Genres: []string{"science fiction"}, Rating: 4.5, Status: StatusWantToRead, UserID: userID}
+		if err := store.Insert(context.Background(), book); err != nil {
+			t.Fatal(err)
+		}
+
+		if book.ID < 1 || book.Version != 1 || book.CreatedAt.IsZero() {
+			t.Fatalf("Insert did not set id, version and created_at: %+v", book)
+		}
+
+		got, err := store.Get(context.Background(), book.ID, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got.Title != book.Title || got.Published != book.Published || got.Pages != book.Pages ||
+			got.Rating != book.Rating || got.Version != book.Version || !equalStrings(got.Genres, book.Genres) {
+			t.Fatalf("Get returned %+v; want %+v", got, book)
+		}
+	})
+
+	t.Run("ReadingStatus", func(t *testing.T) {
+		store, userID := newStore(t)
+		books := seedBooks(t, store, userID)
+
+		book, err := store.Get(context.Background(), books[0].ID, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if book.Status != StatusWantToRead || book.StartedAt != nil || book.FinishedAt != nil {
+			t.Fatalf("new book = %+v; want an unstarted want_to_read book", book)
+		}
+
+		if err := book.SetStatus(StatusReading, time.Date(2024, 3, 1, 18, 30, 0, 0, time.UTC)); err != nil {
+			t.Fatal(err)
+		}
+		book.CurrentPage = 120
+		if err := store.Update(context.Background(), book); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := store.Get(context.Background(), book.ID, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got.Status != StatusReading || got.CurrentPage != 120 || got.StartedAt == nil || got.FinishedAt != nil {
+			t.Fatalf("Get after starting = %+v", got)
+		}
+		if y, m, d := got.StartedAt.Date(); y != 2024 || m != time.March || d != 1 {
+			t.Errorf("started_at = %v; want 2024-03-01", got.StartedAt)
+		}
+	})
+
+	t.Run("GetMissing", func(t *testing.T) {
+		store, userID := newStore(t)
+
+		for _, id := range []int64{0, -1, 999} {
+			if book, err := store.Get(context.Background(), id, userID); !errors.Is(err, ErrRecordNotFound) || book != nil {
+				t.Errorf("Get(%d) = %v, %v; want ErrRecordNotFound", id, book, err)
+			}
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		store, userID := newStore(t)
+		books := seedBooks(t, store, userID)
+
+		book, err := store.Get(context.Background(), books[1].ID, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		book.Title = "Dune Messiah"
+		book.Genres = []string{"science fiction", "classic"}
+		if err := store.Update(context.Background(), book); err != nil {
+			t.Fatal(err)
+		}
+
+		if book.Version != 2 {
+			t.Errorf("version after update = %d; want 2", book.Version)
+		}
+
+		got, err := store.Get(context.Background(), book.ID, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got.Title != "Dune Messiah" || !equalStrings(got.Genres, book.Genres) || got.Version != 2 {
+			t.Errorf("Get after update = %+v", got)
+		}
+	})
+
+	t.Run("UpdateStaleVersion", func(t *testing.T) {
+		store, userID := newStore(t)
+		books := seedBooks(t, store, userID)
+
+		first, _ := store.Get(context.Background(), books[0].ID, userID)
+		second, _ := store.Get(context.Background(), books[0].ID, userID)
+
+		first.Pages = 300
+		if err := store.Update(context.Background(), first); err != nil {
+			t.Fatal(err)
+		}
+
+		second.Pages = 320
+		if err := store.Update(context.Background(), second); !errors.Is(err, ErrEditConflict) {
+			t.Fatalf("Update with a stale version returned %v; want ErrEditConflict", err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		store, userID := newStore(t)
+		books := seedBooks(t, store, userID)
+
+		if err := store.Delete(context.Background(), books[0].ID, userID, 0); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := store.Get(context.Background(), books[0].ID, userID); !errors.Is(err, ErrRecordNotFound) {
+			t.Errorf("Get after Delete returned %v; want ErrRecordNotFound", err)
+		}
+
+		if err := store.Delete(context.Background(), books[0].ID, userID, 0); !errors.Is(err, ErrRecordNotFound) {
+			t.Errorf("deleting a missing book returned %v; want ErrRecordNotFound", err)
+		}
+	})
+
+	t.Run("DeleteStaleVersion", func(t *testing.T) {
+		store, userID := newStore(t)
+		books := seedBooks(t, store, userID)
+
+		if err := store.Delete(context.Background(), books[1].ID, userID, books[1].Version+1); !errors.Is(err, ErrEditConflict) {
+			t.Fatalf("Delete with a stale version returned %v; want ErrEditConflict", err)
+		}
+
+		if err := store.Delete(context.Background(), books[1].ID, userID, books[1].Version); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("GetAllFilters", func(t *testing.T) {
+		store, userID := newStore(t)
+		seedBooks(t, store, userID)
+
+		tests := []struct {
+			name    string
+			title   string
+			genres  []string
+			sort    string
+			want    []string
+			records int
+		}{
+			{"all", "", []string{}, "id", []string{"The Hobbit", "Dune", "The Lord of the Rings", "Neuromancer"}, 4},
+			{"title", "the", []string{}, "id", []string{"The Hobbit", "The Lord of the Rings"}, 2},
+			{"genres", "", []string{"science fiction"}, "-published", []string{"Neuromancer", "Dune"}, 2},
+			{"all genres must match", "", []string{"fantasy", "adventure"}, "id", []string{"The Hobbit"}, 1},
+			{"sort by pages", "", []string{}, "pages", []string{"Neuromancer", "The Hobbit", "Dune", "The Lord of the Rings"}, 4},
+			{"sort by rating descending", "", []string{}, "-rating", []string{"The Lord of the Rings", "The Hobbit", "Dune", "Neuromancer"}, 4},
+			{"no matches", "zzz", []string{}, "id", []string{}, 0},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				filters := Filters{Page: 1, PageSize: 20, Sort: tt.sort, SortSafelist: sortSafelist}
+
+				books, metadata, err := store.GetAll(context.Background(), userID, tt.title, tt.genres, filters)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if got := titles(books); !equalStrings(got, tt.want) {
+					t.Errorf("titles = %q; want %q", got, tt.want)
+				}
+
+				if metadata.TotalRecords != tt.records {
+					t.Errorf("total records = %d; want %d", metadata.TotalRecords, tt.records)
+				}
+			})
+		}
+	})
+
+	t.Run("GetAllPaging", func(t *testing.T) {
+		store, userID := newStore(t)
+		seedBooks(t, store, userID)
+
+		filters := Filters{Page: 2, PageSize: 3, Sort: "id", SortSafelist: sortSafelist}
+
+		books, metadata, err := store.GetAll(context.Background(), userID, "", []string{}, filters)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := titles(books); !equalStrings(got, []string{"Neuromancer"}) {
+			t.Errorf("page 2 titles = %q", got)
+		}
+
+		want := Metadata{CurrentPage: 2, PageSize: 3, FirstPage: 1, LastPage: 2, TotalRecords: 4}
+		if metadata != want {
+			t.Errorf("metadata = %+v; want %+v", metadata, want)
+		}
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		store, userID := newStore(t)
+		seedBooks(t, store, userID)
+
+		filters := Filters{Page: 1, PageSize: 20, Sort: "id", SortSafelist: sortSafelist}
+
+		books, metadata, err := store.Search(context.Background(), userID, "lord rings", filters)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := titles(books); !equalStrings(got, []string{"The Lord of the Rings"}) {
+			t.Errorf("titles = %q", got)
+		}
+
+		if metadata.TotalRecords != 1 {
+			t.Errorf("total records = %d; want 1", metadata.TotalRecords)
+		}
+
+		books, _, err = store.Search(context.Background(), userID, "dragons", filters)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(books) != 0 {
+			t.Errorf("search with no matches returned %q", titles(books))
+		}
+	})
+	t.Run("OtherUsersBooks", func(t *testing.T) {
+		models := newModels(t)
+		alice := seedUser(t, models.Users, "alice@example.com")
+		bob := seedUser(t, models.Users, "bob@example.com")
+
+		books := seedBooks(t, models.Books, alice.ID)
+		filters := Filters{Page: 1, PageSize: 20, Sort: "id", SortSafelist: sortSafelist}
+
+		if _, err := models.Books.Get(context.Background(), books[0].ID, bob.ID); !errors.Is(err, ErrRecordNotFound) {
+			t.Errorf("Get of someone else's book returned %v; want ErrRecordNotFound", err)
+		}
+
+		if got, _, err := models.Books.GetAll(context.Background(), bob.ID, "", []string{}, filters); err != nil || len(got) != 0 {
+			t.Errorf("GetAll for a user with no books = %q, %v", titles(got), err)
+		}
+
+		if got, _, err := models.Books.Search(context.Background(), bob.ID, "hobbit", filters); err != nil || len(got) != 0 {
+			t.Errorf("Search for a user with no books = %q, %v", titles(got), err)
+		}
+
+		if err := models.Books.Delete(context.Background(), books[0].ID, bob.ID, 0); !errors.Is(err, ErrRecordNotFound) {
+			t.Errorf("Delete of someone else's book returned %v; want ErrRecordNotFound", err)
+		}
+
+		stolen := *books[1]
+		stolen.UserID = bob.ID
+		stolen.Title = "Mine now"
+		if err := models.Books.Update(context.Background(), &stolen); !errors.Is(err, ErrEditConflict) {
+			t.Errorf("Update of someone else's book returned %v; want ErrEditConflict", err)
+		}
+
+		if got, _, err := models.Books.GetAll(context.Background(), alice.ID, "", []string{}, filters); err != nil || len(got) != 4 {
+			t.Errorf("GetAll for the owner = %q, %v; want all 4 books", titles(got), err)
+		}
+	})
+}
+
+func TestValidateBook(t *testing.T) {
+	valid := func() *Book {
+		return &Book{Title: "Dune", Published: 1965, Pages: 412, Genres: []string{"Science Fiction"}, Rating: 4.5, Status: StatusReading, CurrentPage: 100}
+	}
+
+	tests := []struct {
+		name   string
+		change func(b *Book)
+		want   map[string]string //the fields that should have an error and their message
+	}{
+		{name: "valid", change: func(b *Book) {}, want: map[string]string{}},
+		{name: "missing title", change: func(b *Book) { b.Title = "" }, want: map[string]string{"title": "must be provided"}},
+		{name: "long title", change: func(b *Book) { b.Title = strings.Repeat("a", 501) }, want: map[string]string{"title": "must not be more than 500 bytes long"}},
+		{name: "missing published", change: func(b *Book) { b.Published = 0 }, want: map[string]string{"published": "must be provided"}},
+		{name: "negative published", change: func(b *Book) { b.Published = -5 }, want: map[string]string{"published": "must be a positive year"}},
+		{name: "future published", change: func(b *Book) { b.Published = time.Now().Year() + 1 }, want: map[string]string{"published": "must not be in the future"}},
+		{name: "missing pages", change: func(b *Book) { b.Pages = 0 }, want: map[string]string{"pages": "must be provided"}},
+		{name: "negative pages", change: func(b *Book) { b.Pages = -1 }, want: map[string]string{"pages": "must be a positive integer"}},
+		{name: "missing genres", change: func(b *Book) { b.Genres = nil }, want: map[string]string{"genres": "must be provided"}},
+		{name: "no genres", change: func(b *Book) { b.Genres = []string{} }, want: map[string]string{"genres": "must contain at least 1 genre"}},
+		{name: "too many genres", change: func(b *Book) { b.Genres = []string{"a", "b", "c", "d", "e", "f"} }, want: map[string]string{"genres": "must not contain more than 5 genres"}},
+		{name: "duplicate genres", change: func(b *Book) { b.Genres = []string{"a", "a"} }, want: map[string]string{"genres": "must not contain duplicate values"}},
+		{name: "negative rating", change: func(b *Book) { b.Rating = -0.5 }, want: map[string]string{"rating": "must not be less than 0"}},
+		{name: "rating above 5", change: func(b *Book) { b.Rating = 5.5 }, want: map[string]string{"rating": "must not be more than 5"}},
+		{name: "unknown status", change: func(b *Book) { b.Status = "skimmed" }, want: map[string]string{"status": "must be want_to_read, reading, finished or abandoned"}},
+		{name: "negative current page", change: func(b *Book) { b.CurrentPage = -1 }, want: map[string]string{"current_page": "must not be negative"}},
+		{name: "current page past the end", change: func(b *Book) { b.CurrentPage = 413 }, want: map[string]string{"current_page": "must not be more than the number of pages"}},
+		{name: "current page on the last page", change: func(b *Book) { b.CurrentPage = 412 }, want: map[string]string{}},
+		//a bad pages value is reported once, not again as a current_page error
+		{name: "bad pages with a current page", change: func(b *Book) { b.Pages = -1 }, want: map[string]string{"pages": "must be a positive integer"}},
+		{name: "missing pages with a current page", change: func(b *Book) { b.Pages = 0; b.CurrentPage = 10 }, want: map[string]string{"pages": "must be provided"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			book := valid()
+			tt.change(book)
+
+			v := validator.New()
+			ValidateBook(v, book)
+
+			if len(v.Errors) != len(tt.want) {
+				t.Fatalf("errors = %v; want %v", v.Errors, tt.want)
+			}
+			for field, message := range tt.want {
+				if v.Errors[field] != message {
+					t.Errorf("errors[%s] = %q; want %q", field, v.Errors[field], message)
+				}
+			}
+		})
+	}
+}
@@ -1,11 +1,66 @@
 package data
 
-import "database/sql"
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
 
 //this file is intended to encapsulate the different models being used
 
+// these are the errors the models return for problems the client can do something about
+// the handlers check for them with errors.Is, which only works because every model returns these exact values
+// creating a new errors.New("record not found") somewhere else would never match
+var (
+	// ErrRecordNotFound is returned when there is no record with the id that was asked for
+	ErrRecordNotFound = errors.New("record not found")
+	// ErrEditConflict is returned by Update and Delete when the record was changed by someone else after it was read
+	ErrEditConflict = errors.New("edit conflict")
+	// ErrDuplicate is returned when an insert or update would break a unique constraint
+	ErrDuplicate = errors.New("duplicate record")
+)
+
+// QueryTimeout is the longest a single query can run
+// every store method takes a context and the handlers pass in the request's, so a query also stops as soon as the client goes away
+const QueryTimeout = 3 * time.Second
+
+// BookStore is the set of CRUD operations the handlers use to work with books
+// the handlers only know about this interface, so the books can be stored in PostgreSQL (BookModel),
+// SQLite (SQLiteBookModel) or in memory (MemoryBookModel) without the handlers changing
+// Update only saves a book whose version still matches the stored one, and Delete does the same when it is given a version other than 0
+// every book belongs to a user: Insert and Update use book.UserID and the other methods only see the books of the userID they are given
+// every method takes a context; the SQL models cancel the query when it is done or after QueryTimeout, whichever comes first
+type BookStore interface {
+	Insert(ctx context.Context, book *Book) error
+	Get(ctx context.Context, id int64, userID int64) (*Book, error)
+	Update(ctx context.Context, book *Book) error
+	Delete(ctx context.Context, id int64, userID int64, version int32) error
+	GetAll(ctx context.Context, userID int64, title string, genres []string, filters Filters) ([]*Book, Metadata, error)
+	Search(ctx context.Context, userID int64, q string, filters Filters) ([]*Book, Metadata, error)
+}
+
+// AuthorStore is the set of operations for authors and for the links between authors and books
+// it is implemented by AuthorModel (PostgreSQL), SQLiteAuthorModel and MemoryAuthorModel
+// authors belong to a user like books do: Insert and Update use author.UserID and Get, Delete and GetAll only see userID's authors
+type AuthorStore interface {
+	Insert(ctx context.Context, author *Author) error
+	Get(ctx context.Context, id int64, userID int64) (*Author, error)
+	Update(ctx context.Context, author *Author) error
+	Delete(ctx context.Context, id int64, userID int64) error
+	GetAll(ctx context.Context, userID int64, name string, filters Filters) ([]*Author, Metadata, error)
+	GetBooks(ctx context.Context, authorID int64, userID int64, filters Filters) ([]*Book, Metadata, error)
+	SetForBook(ctx context.Context, bookID int64, authors []BookAuthor) error
+	LoadForBooks(ctx context.Context, books []*Book) error
+}
+
 type Models struct {
-	Books BookModel
+	Books       BookStore
+	Authors     AuthorStore
+	Progress    ProgressStore
+	Users       UserStore
+	Tokens      TokenStore
+	Permissions PermissionStore
 }
 
 // the function below just returns the model
@@ -13,6 +68,40 @@ type Models struct {
 // this helps us connect to the database and then implement CRUD operations
 func NewModels(db *sql.DB) Models {
 	return Models{
-		Books: BookModel{DB: db},
+		Books:       BookModel{DB: db},
+		Authors:     AuthorModel{DB: db},
+		Progress:    ProgressModel{DB: db},
+		Users:       UserModel{DB: db},
+		Tokens:      TokenModel{DB: db},
+		Permissions: PermissionModel{DB: db},
+	}
+}
+
+// NewSQLiteModels returns the models backed by a SQLite database, which is handy for single-user installs
+func NewSQLiteModels(db *sql.DB) Models {
+	return Models{
+		Books:       SQLiteBookModel{DB: db},
+		Authors:     SQLiteAuthorModel{DB: db},
+		Progress:    SQLiteProgressModel{DB: db},
+		Users:       SQLiteUserModel{DB: db},
+		Tokens:      SQLiteTokenModel{DB: db},
+		Permissions: SQLitePermissionModel{DB: db},
+	}
+}
+
+// NewMemoryModels returns models that keep everything in memory
+// nothing is saved when the program stops, so this is mostly useful for tests
+func NewMemoryModels() Models {
+	books := NewMemoryBookModel()
+	tokens := NewMemoryTokenModel()
+	permissions := NewMemoryPermissionModel()
+
+	return Models{
+		Books:       books,
+		Authors:     NewMemoryAuthorModel(books),
+		Progress:    NewMemoryProgressModel(books),
+		Users:       NewMemoryUserModel(tokens, permissions),
+		Tokens:      tokens,
+		Permissions: permissions,
 	}
 }
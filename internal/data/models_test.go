@@ -0,0 +1,58 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSQLiteCancelledContext(t *testing.T) {
+	testCancelledContext(t, newSQLiteModels)
+}
+
+func TestPostgresCancelledContext(t *testing.T) {
+	testCancelledContext(t, newPostgresModels)
+}
+
+// testCancelledContext checks that every SQL store gives up on a query once the request's context is cancelled
+func testCancelledContext(t *testing.T, newModels func(t *testing.T) Models) {
+	models := newModels(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := map[string]func() error{
+		"Books.Get": func() error {
+			_, err := models.Books.Get(ctx, 1, 1)
+			return err
+		},
+		"Authors.GetAll": func() error {
+			_, _, err := models.Authors.GetAll(ctx, 1, "", Filters{Page: 1, PageSize: 20, Sort: "id", SortSafelist: []string{"id"}})
+			return err
+		},
+		"Authors.LoadForBooks": func() error {
+			return models.Authors.LoadForBooks(ctx, []*Book{{ID: 1}})
+		},
+		"Progress.GetForBook": func() error {
+			_, err := models.Progress.GetForBook(ctx, 1)
+			return err
+		},
+		"Users.GetByEmail": func() error {
+			_, err := models.Users.GetByEmail(ctx, "alice@example.com")
+			return err
+		},
+		"Tokens.DeleteAllForUser": func() error {
+			return models.Tokens.DeleteAllForUser(ctx, ScopeAuthentication, 1)
+		},
+		"Permissions.GetAllForUser": func() error {
+			_, err := models.Permissions.GetAllForUser(ctx, 1)
+			return err
+		},
+	}
+
+	for name, call := range calls {
+		if err := call(); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s with a cancelled context = %v; want %v", name, err, context.Canceled)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// the permission codes; the migrations add the same codes to the permissions table
+const (
+	PermissionBooksRead  = "books:read"
+	PermissionBooksWrite = "books:write"
+)
+
+// AllPermissions is every permission code there is
+var AllPermissions = []string{PermissionBooksRead, PermissionBooksWrite}
+
+// DefaultPermissions are the permissions every new user is given so they can look after their own reading list
+// books and authors belong to the user who added them and nothing is shared, so books:read only ever shows a user their own list
+// taking books:write away with the permissions subcommand freezes that list: the user can still read it but not change it
+// a user who never had books:write has an empty list, so books:read on its own doesn't make a reader of anyone else's books
+var DefaultPermissions = []string{PermissionBooksRead, PermissionBooksWrite}
+
+// Permissions holds the permission codes a user has, like "books:read"
+type Permissions []string
+
+// Include reports whether the code is one of the permissions
+func (p Permissions) Include(code string) bool {
+	for i := range p {
+		if code == p[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// PermissionStore looks up and grants permissions
+// it is implemented by PermissionModel (PostgreSQL), SQLitePermissionModel and MemoryPermissionModel
+// codes that aren't in the permissions table are ignored by AddForUser and RemoveForUser
+type PermissionStore interface {
+	GetAllForUser(ctx context.Context, userID int64) (Permissions, error)
+	AddForUser(ctx context.Context, userID int64, codes ...string) error
+	RemoveForUser(ctx context.Context, userID int64, codes ...string) error
+}
+
+// addPermissionsQuery grants the codes in $2 to the user $1; UserModel.Insert runs it too, inside its transaction
+const addPermissionsQuery = `
+	INSERT INTO users_permissions (user_id, permission_id)
+	SELECT $1, permissions.id FROM permissions WHERE permissions.code = ANY($2)
+	ON CONFLICT DO NOTHING`
+
+// PermissionModel stores permissions in PostgreSQL
+type PermissionModel struct {
+	DB *sql.DB
+}
+
+func (p PermissionModel) GetAllForUser(ctx context.Context, userID int64) (Permissions, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	query := `
+	SELECT permissions.code
+	FROM permissions
+	INNER JOIN users_permissions ON users_permissions.permission_id = permissions.id
+	WHERE users_permissions.user_id = $1
+	ORDER BY permissions.code`
+
+	return scanPermissions(ctx, p.DB, query, userID)
+}
+
+func (p PermissionModel) AddForUser(ctx context.Context, userID int64, codes ...string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	_, err := p.DB.ExecContext(ctx, addPermissionsQuery, userID, pq.Array(codes))
+	return err
+}
+
+func (p PermissionModel) RemoveForUser(ctx context.Context, userID int64, codes ...string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	query := `
+	DELETE FROM users_permissions
+	WHERE user_id = $1
+	AND permission_id IN (SELECT id FROM permissions WHERE code = ANY($2))`
+
+	_, err := p.DB.ExecContext(ctx, query, userID, pq.Array(codes))
+	return err
+}
+
+// scanPermissions runs a query that selects permission codes; the PostgreSQL and SQLite models share it
+func scanPermissions(ctx context.Context, db *sql.DB, query string, args ...interface{}) (Permissions, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := Permissions{}
+
+	for rows.Next() {
+		var permission string
+
+		if err := rows.Scan(&permission); err != nil {
+			return nil, err
+		}
+
+		permissions = append(permissions, permission)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
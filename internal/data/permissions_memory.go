@@ -0,0 +1,59 @@
+package data
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"readinglist/internal/validator"
+)
+
+// MemoryPermissionModel keeps each user's permission codes in memory
+type MemoryPermissionModel struct {
+	mu          sync.Mutex
+	permissions map[int64]Permissions
+}
+
+// NewMemoryPermissionModel returns an in-memory permission store where nobody has any permissions yet
+func NewMemoryPermissionModel() *MemoryPermissionModel {
+	return &MemoryPermissionModel{permissions: make(map[int64]Permissions)}
+}
+
+func (m *MemoryPermissionModel) GetAllForUser(ctx context.Context, userID int64) (Permissions, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append(Permissions{}, m.permissions[userID]...), nil
+}
+
+// AddForUser only knows about the codes in AllPermissions, which are all the codes the migrations add
+func (m *MemoryPermissionModel) AddForUser(ctx context.Context, userID int64, codes ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	permissions := m.permissions[userID]
+	for _, code := range codes {
+		if validator.PermittedValue(code, AllPermissions...) && !permissions.Include(code) {
+			permissions = append(permissions, code)
+		}
+	}
+
+	sort.Strings(permissions)
+	m.permissions[userID] = permissions
+	return nil
+}
+
+func (m *MemoryPermissionModel) RemoveForUser(ctx context.Context, userID int64, codes ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	kept := Permissions{}
+	for _, code := range m.permissions[userID] {
+		if !validator.PermittedValue(code, codes...) {
+			kept = append(kept, code)
+		}
+	}
+
+	m.permissions[userID] = kept
+	return nil
+}
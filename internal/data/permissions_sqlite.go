@@ -0,0 +1,52 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+)
+
+// SQLitePermissionModel stores permissions in SQLite
+type SQLitePermissionModel struct {
+	DB *sql.DB
+}
+
+func (p SQLitePermissionModel) GetAllForUser(ctx context.Context, userID int64) (Permissions, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	query := `
+	SELECT permissions.code
+	FROM permissions
+	INNER JOIN users_permissions ON users_permissions.permission_id = permissions.id
+	WHERE users_permissions.user_id = ?
+	ORDER BY permissions.code`
+
+	return scanPermissions(ctx, p.DB, query, userID)
+}
+
+// sqliteAddPermissionsQuery is addPermissionsQuery for SQLite; SQLiteUserModel.Insert runs it too, inside its transaction
+const sqliteAddPermissionsQuery = `
+	INSERT OR IGNORE INTO users_permissions (user_id, permission_id)
+	SELECT ?, permissions.id FROM permissions WHERE permissions.code IN (SELECT value FROM json_each(?))`
+
+// AddForUser passes the codes in as a JSON array, the same way SQLiteAuthorModel.LoadForBooks passes ids
+func (p SQLitePermissionModel) AddForUser(ctx context.Context, userID int64, codes ...string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	_, err := p.DB.ExecContext(ctx, sqliteAddPermissionsQuery, userID, jsonArray{&codes})
+	return err
+}
+
+func (p SQLitePermissionModel) RemoveForUser(ctx context.Context, userID int64, codes ...string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	query := `
+	DELETE FROM users_permissions
+	WHERE user_id = ?
+	AND permission_id IN (SELECT id FROM permissions WHERE code IN (SELECT value FROM json_each(?)))`
+
+	_, err := p.DB.ExecContext(ctx, query, userID, jsonArray{&codes})
+	return err
+}
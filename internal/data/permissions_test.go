@@ -0,0 +1,69 @@
+package data
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMemoryPermissionStore(t *testing.T) {
+	testPermissionStore(t, newMemoryModels)
+}
+
+func TestSQLitePermissionStore(t *testing.T) {
+	testPermissionStore(t, newSQLiteModels)
+}
+
+func TestPostgresPermissionStore(t *testing.T) {
+	testPermissionStore(t, newPostgresModels)
+}
+
+func testPermissionStore(t *testing.T, newModels func(t *testing.T) Models) {
+	models := newModels(t)
+	alice := seedUser(t, models.Users, "alice@example.com")
+	bob := seedUser(t, models.Users, "bob@example.com")
+
+	if err := models.Permissions.AddForUser(context.Background(), alice.ID, PermissionBooksRead); err != nil {
+		t.Fatal(err)
+	}
+
+	//adding a permission twice is fine, and codes that don't exist are ignored
+	if err := models.Permissions.AddForUser(context.Background(), alice.ID, PermissionBooksWrite, PermissionBooksRead, "books:burn"); err != nil {
+		t.Fatal(err)
+	}
+
+	permissions, err := models.Permissions.GetAllForUser(context.Background(), alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !equalStrings(permissions, []string{PermissionBooksRead, PermissionBooksWrite}) {
+		t.Errorf("permissions = %q; want books:read and books:write", permissions)
+	}
+
+	if !permissions.Include(PermissionBooksWrite) || permissions.Include("books:burn") {
+		t.Errorf("Include gave the wrong answer for %q", permissions)
+	}
+
+	//taking books:write away leaves a read-only member
+	if err := models.Permissions.RemoveForUser(context.Background(), alice.ID, PermissionBooksWrite, "books:burn"); err != nil {
+		t.Fatal(err)
+	}
+
+	permissions, err = models.Permissions.GetAllForUser(context.Background(), alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !equalStrings(permissions, []string{PermissionBooksRead}) {
+		t.Errorf("permissions after RemoveForUser = %q; want books:read", permissions)
+	}
+
+	permissions, err = models.Permissions.GetAllForUser(context.Background(), bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(permissions) != 0 {
+		t.Errorf("a user with no permissions got %q", permissions)
+	}
+}
@@ -0,0 +1,201 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"readinglist/internal/validator"
+)
+
+// the reading statuses a book can be in
+// the database has the same list in a CHECK constraint on books.status
+const (
+	StatusWantToRead = "want_to_read"
+	StatusReading    = "reading"
+	StatusFinished   = "finished"
+	StatusAbandoned  = "abandoned"
+)
+
+// ReadingStatuses is every status in the order they are shown to people
+var ReadingStatuses = []string{StatusWantToRead, StatusReading, StatusFinished, StatusAbandoned}
+
+// statusTransitions lists the statuses a book is allowed to move to from each status
+// staying in the same status is always allowed, so it isn't listed here
+var statusTransitions = map[string][]string{
+	StatusWantToRead: {StatusReading, StatusFinished, StatusAbandoned},
+	StatusReading:    {StatusWantToRead, StatusFinished, StatusAbandoned},
+	StatusFinished:   {StatusReading},
+	StatusAbandoned:  {StatusWantToRead, StatusReading},
+}
+
+// ErrInvalidTransition is returned by SetStatus when a book can't move from its current status to the new one
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// CanTransition reports whether a book in status from is allowed to move to status to
+func CanTransition(from, to string) bool {
+	if from == to {
+		return true
+	}
+
+	return validator.PermittedValue(to, statusTransitions[from]...)
+}
+
+// SetStatus moves the book to a new reading status and keeps the dates and current page in step with it
+// starting a book sets StartedAt, finishing it sets FinishedAt and moves the current page to the last page,
+// reading a finished or abandoned book again starts a new read with a new StartedAt and no FinishedAt,
+// and going back to want_to_read clears both dates so the next read starts fresh
+func (b *Book) SetStatus(status string, on time.Time) error {
+	if !validator.PermittedValue(status, ReadingStatuses...) {
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidTransition, status)
+	}
+
+	if b.Status == "" {
+		b.Status = StatusWantToRead
+	}
+
+	if !CanTransition(b.Status, status) {
+		return fmt.Errorf("%w: a %s book can't be marked as %s", ErrInvalidTransition, b.Status, status)
+	}
+
+	if status == b.Status {
+		return nil
+	}
+
+	day := on.UTC().Truncate(24 * time.Hour)
+
+	switch status {
+	case StatusWantToRead:
+		b.StartedAt = nil
+		b.FinishedAt = nil
+		b.CurrentPage = 0
+
+	case StatusReading:
+		//re-reading a finished book starts it over
+		if b.Status == StatusFinished {
+			b.CurrentPage = 0
+		}
+		//picking a finished or abandoned book up again is a new read, so it gets a new start date and loses its end date
+		//an abandoned book keeps its current page so the reader can carry on from where they stopped
+		if b.Status == StatusFinished || b.Status == StatusAbandoned {
+			b.FinishedAt = nil
+			b.StartedAt = &day
+		}
+		if b.StartedAt == nil {
+			b.StartedAt = &day
+		}
+
+	case StatusFinished:
+		if b.StartedAt == nil {
+			b.StartedAt = &day
+		}
+		b.FinishedAt = &day
+		b.CurrentPage = b.Pages
+
+	case StatusAbandoned:
+		if b.StartedAt == nil {
+			b.StartedAt = &day
+		}
+		b.FinishedAt = &day
+	}
+
+	b.Status = status
+	return nil
+}
+
+// ReadingSession is one sitting with a book
+type ReadingSession struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"-"`
+	BookID    int64     `json:"book_id"`
+	PagesRead int       `json:"pages_read"`
+	Minutes   int       `json:"minutes"`
+	ReadOn    time.Time `json:"read_on"`
+}
+
+func ValidateReadingSession(v *validator.Validator, session *ReadingSession) {
+	v.Check(session.PagesRead >= 0, "pages_read", "must not be negative")
+	v.Check(session.Minutes >= 0, "minutes", "must not be negative")
+	v.Check(session.PagesRead > 0 || session.Minutes > 0, "pages_read", "pages_read or minutes must be more than zero")
+	v.Check(session.Minutes <= 24*60, "minutes", "must not be more than a day")
+	v.Check(!session.ReadOn.IsZero(), "date", "must be provided")
+	v.Check(session.ReadOn.Before(time.Now().Add(24*time.Hour)), "date", "must not be in the future")
+}
+
+// ProgressStore records reading sessions
+// it is implemented by ProgressModel (PostgreSQL), SQLiteProgressModel and MemoryProgressModel
+type ProgressStore interface {
+	Insert(ctx context.Context, session *ReadingSession) error
+	GetForBook(ctx context.Context, bookID int64) ([]*ReadingSession, error)
+}
+
+// ProgressModel stores reading sessions in PostgreSQL
+type ProgressModel struct {
+	DB *sql.DB
+}
+
+func (p ProgressModel) Insert(ctx context.Context, session *ReadingSession) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	query := `
+	INSERT INTO reading_sessions (book_id, pages_read, minutes, read_on)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, created_at`
+
+	args := []interface{}{session.BookID, session.PagesRead, session.Minutes, session.ReadOn}
+
+	return p.DB.QueryRowContext(ctx, query, args...).Scan(&session.ID, &session.CreatedAt)
+}
+
+// GetForBook returns the sessions logged for a book, the most recent first
+func (p ProgressModel) GetForBook(ctx context.Context, bookID int64) ([]*ReadingSession, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	query := `
+	SELECT id, created_at, book_id, pages_read, minutes, read_on
+	FROM reading_sessions
+	WHERE book_id = $1
+	ORDER BY read_on DESC, id DESC`
+
+	return scanSessions(ctx, p.DB, query, bookID)
+}
+
+// scanSessions runs a query that selects reading sessions and scans every row
+// the PostgreSQL and SQLite models select the same columns so they share it
+func scanSessions(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]*ReadingSession, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*ReadingSession{}
+
+	for rows.Next() {
+		var session ReadingSession
+
+		err := rows.Scan(
+			&session.ID,
+			&session.CreatedAt,
+			&session.BookID,
+			&session.PagesRead,
+			&session.Minutes,
+			&session.ReadOn,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, &session)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
@@ -0,0 +1,70 @@
+package data
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryProgressModel keeps reading sessions in memory
+// like MemoryAuthorModel it holds on to the book store so it can check the book exists
+type MemoryProgressModel struct {
+	mu       sync.Mutex
+	books    *MemoryBookModel
+	sessions []ReadingSession
+	nextID   int64
+}
+
+// NewMemoryProgressModel returns an empty in-memory session store for the books in books
+func NewMemoryProgressModel(books *MemoryBookModel) *MemoryProgressModel {
+	return &MemoryProgressModel{
+		books:  books,
+		nextID: 1,
+	}
+}
+
+// Insert returns ErrRecordNotFound if the book doesn't exist, like a foreign key error in the SQL versions
+func (m *MemoryProgressModel) Insert(ctx context.Context, session *ReadingSession) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.books.exists(session.BookID) {
+		return ErrRecordNotFound
+	}
+
+	session.ID = m.nextID
+	session.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	m.nextID++
+
+	m.sessions = append(m.sessions, *session)
+	return nil
+}
+
+// GetForBook skips the sessions of a book that has been deleted, which the SQL versions do with ON DELETE CASCADE
+func (m *MemoryProgressModel) GetForBook(ctx context.Context, bookID int64) ([]*ReadingSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sessions := []*ReadingSession{}
+
+	if !m.books.exists(bookID) {
+		return sessions, nil
+	}
+
+	for _, session := range m.sessions {
+		if session.BookID == bookID {
+			session := session
+			sessions = append(sessions, &session)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].ReadOn.Equal(sessions[j].ReadOn) {
+			return sessions[i].ReadOn.After(sessions[j].ReadOn)
+		}
+		return sessions[i].ID > sessions[j].ID
+	})
+
+	return sessions, nil
+}
@@ -0,0 +1,46 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// SQLiteProgressModel stores reading sessions in SQLite
+type SQLiteProgressModel struct {
+	DB *sql.DB
+}
+
+func (p SQLiteProgressModel) Insert(ctx context.Context, session *ReadingSession) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	session.CreatedAt = time.Now().UTC().Truncate(time.Second)
+
+	query := `
+	INSERT INTO reading_sessions (created_at, book_id, pages_read, minutes, read_on)
+	VALUES (?, ?, ?, ?, ?)`
+
+	args := []interface{}{session.CreatedAt, session.BookID, session.PagesRead, session.Minutes, session.ReadOn}
+
+	result, err := p.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	session.ID, err = result.LastInsertId()
+	return err
+}
+
+func (p SQLiteProgressModel) GetForBook(ctx context.Context, bookID int64) ([]*ReadingSession, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	query := `
+	SELECT id, created_at, book_id, pages_read, minutes, read_on
+	FROM reading_sessions
+	WHERE book_id = ?
+	ORDER BY read_on DESC, id DESC`
+
+	return scanSessions(ctx, p.DB, query, bookID)
+}
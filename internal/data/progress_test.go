@@ -0,0 +1,144 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSetStatus(t *testing.T) {
+	day := time.Date(2024, 5, 10, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		from, to string
+		ok       bool
+	}{
+		{StatusWantToRead, StatusReading, true},
+		{StatusWantToRead, StatusFinished, true},
+		{StatusReading, StatusFinished, true},
+		{StatusReading, StatusAbandoned, true},
+		{StatusFinished, StatusReading, true},
+		{StatusFinished, StatusWantToRead, false},
+		{StatusFinished, StatusAbandoned, false},
+		{StatusAbandoned, StatusFinished, false},
+		{StatusReading, StatusReading, true},
+		{StatusReading, "skimmed", false},
+	}
+
+	for _, tt := range tests {
+		book := &Book{Pages: 300, Status: tt.from}
+
+		err := book.SetStatus(tt.to, day)
+		if tt.ok && err != nil {
+			t.Errorf("%s -> %s returned %v", tt.from, tt.to, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrInvalidTransition) {
+			t.Errorf("%s -> %s returned %v; want ErrInvalidTransition", tt.from, tt.to, err)
+		}
+	}
+
+	book := &Book{Pages: 300, Status: StatusWantToRead}
+
+	if err := book.SetStatus(StatusFinished, day); err != nil {
+		t.Fatal(err)
+	}
+	if book.CurrentPage != 300 || book.StartedAt == nil || book.FinishedAt == nil {
+		t.Errorf("finishing a book left %+v", book)
+	}
+
+	if err := book.SetStatus(StatusReading, day); err != nil {
+		t.Fatal(err)
+	}
+	if book.CurrentPage != 0 || book.FinishedAt != nil {
+		t.Errorf("re-reading a book left %+v", book)
+	}
+
+	//an abandoned book that is picked up again is being read, so it can't still have a finish date
+	started := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	abandoned := time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC)
+	book = &Book{Pages: 300, Status: StatusAbandoned, CurrentPage: 120, StartedAt: &started, FinishedAt: &abandoned}
+
+	if err := book.SetStatus(StatusReading, day); err != nil {
+		t.Fatal(err)
+	}
+	if book.FinishedAt != nil {
+		t.Errorf("picking up an abandoned book kept FinishedAt %v", book.FinishedAt)
+	}
+	if want := day.Truncate(24 * time.Hour); book.StartedAt == nil || !book.StartedAt.Equal(want) {
+		t.Errorf("picking up an abandoned book set StartedAt to %v; want %v", book.StartedAt, want)
+	}
+	if book.CurrentPage != 120 {
+		t.Errorf("picking up an abandoned book moved the current page to %d; want 120", book.CurrentPage)
+	}
+}
+
+func TestMemoryProgressStore(t *testing.T) {
+	testProgressStore(t, newMemoryModels)
+}
+
+func TestSQLiteProgressStore(t *testing.T) {
+	testProgressStore(t, newSQLiteModels)
+}
+
+func TestPostgresProgressStore(t *testing.T) {
+	testProgressStore(t, newPostgresModels)
+}
+
+func testProgressStore(t *testing.T, newModels func(t *testing.T) Models) {
+	t.Run("InsertAndGetForBook", func(t *testing.T) {
+		models := newModels(t)
+		user := seedUser(t, models.Users, "reader@example.com")
+		books := seedBooks(t, models.Books, user.ID)
+
+		sessions := []*ReadingSession{
+			{BookID: books[0].ID, PagesRead: 30, Minutes: 45, ReadOn: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+			{BookID: books[0].ID, PagesRead: 12, Minutes: 20, ReadOn: time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC)},
+			{BookID: books[1].ID, PagesRead: 50, Minutes: 60, ReadOn: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)},
+		}
+
+		for _, session := range sessions {
+			if err := models.Progress.Insert(context.Background(), session); err != nil {
+				t.Fatal(err)
+			}
+			if session.ID < 1 {
+				t.Fatalf("Insert did not set the id: %+v", session)
+			}
+		}
+
+		got, err := models.Progress.GetForBook(context.Background(), books[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(got) != 2 || got[0].PagesRead != 12 || got[1].PagesRead != 30 {
+			t.Fatalf("GetForBook returned %+v; want the two sessions for the book, newest first", got)
+		}
+		if y, m, d := got[0].ReadOn.Date(); y != 2024 || m != time.May || d != 3 {
+			t.Errorf("read_on = %v; want 2024-05-03", got[0].ReadOn)
+		}
+	})
+
+	t.Run("DeletedBook", func(t *testing.T) {
+		models := newModels(t)
+		user := seedUser(t, models.Users, "reader@example.com")
+		books := seedBooks(t, models.Books, user.ID)
+
+		session := &ReadingSession{BookID: books[2].ID, PagesRead: 10, ReadOn: time.Now()}
+		if err := models.Progress.Insert(context.Background(), session); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := models.Books.Delete(context.Background(), books[2].ID, user.ID, 0); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := models.Progress.GetForBook(context.Background(), books[2].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 0 {
+			t.Errorf("GetForBook after the book was deleted returned %d sessions; want 0", len(got))
+		}
+	})
+}
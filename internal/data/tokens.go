@@ -0,0 +1,95 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"time"
+
+	"readinglist/internal/validator"
+)
+
+// the scopes a token can have; a token can only be used for the job it was made for
+const (
+	ScopeAuthentication = "authentication"
+)
+
+// Token is a stateful bearer token
+// the client only ever sees the plaintext, and only the SHA-256 hash is stored
+type Token struct {
+	Plaintext string    `json:"token"`
+	Hash      []byte    `json:"-"`
+	UserID    int64     `json:"-"`
+	Expiry    time.Time `json:"expiry"`
+	Scope     string    `json:"-"`
+}
+
+// GenerateToken makes a new random token for a user that lasts for ttl
+// the token still has to be saved with a TokenStore before it can be used
+func GenerateToken(userID int64, ttl time.Duration, scope string) (*Token, error) {
+	token := &Token{
+		UserID: userID,
+		Expiry: time.Now().Add(ttl).UTC().Truncate(time.Second),
+		Scope:  scope,
+	}
+
+	//16 random bytes come out as a 26 character base32 string once the padding is left off
+	randomBytes := make([]byte, 16)
+
+	if _, err := rand.Read(randomBytes); err != nil {
+		return nil, err
+	}
+
+	token.Plaintext = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)
+
+	hash := sha256.Sum256([]byte(token.Plaintext))
+	token.Hash = hash[:]
+
+	return token, nil
+}
+
+func ValidateTokenPlaintext(v *validator.Validator, tokenPlaintext string) {
+	v.Check(tokenPlaintext != "", "token", "must be provided")
+	v.Check(len(tokenPlaintext) == 26, "token", "must be 26 bytes long")
+}
+
+// TokenStore saves tokens; looking a token up is done with UserStore.GetForToken
+// it is implemented by TokenModel (PostgreSQL), SQLiteTokenModel and MemoryTokenModel
+type TokenStore interface {
+	Insert(ctx context.Context, token *Token) error
+	DeleteAllForUser(ctx context.Context, scope string, userID int64) error
+}
+
+// TokenModel stores tokens in PostgreSQL
+type TokenModel struct {
+	DB *sql.DB
+}
+
+func (t TokenModel) Insert(ctx context.Context, token *Token) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	query := `
+	INSERT INTO tokens (hash, user_id, expiry, scope)
+	VALUES ($1, $2, $3, $4)`
+
+	args := []interface{}{token.Hash, token.UserID, token.Expiry, token.Scope}
+
+	_, err := t.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+// DeleteAllForUser removes every token with the scope that belongs to the user
+func (t TokenModel) DeleteAllForUser(ctx context.Context, scope string, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	query := `
+	DELETE FROM tokens
+	WHERE scope = $1 AND user_id = $2`
+
+	_, err := t.DB.ExecContext(ctx, query, scope, userID)
+	return err
+}
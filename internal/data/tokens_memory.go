@@ -0,0 +1,49 @@
+package data
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryTokenModel keeps tokens in memory, keyed by their hash
+type MemoryTokenModel struct {
+	mu     sync.Mutex
+	tokens map[string]Token
+}
+
+// NewMemoryTokenModel returns an empty in-memory token store
+func NewMemoryTokenModel() *MemoryTokenModel {
+	return &MemoryTokenModel{tokens: make(map[string]Token)}
+}
+
+// Insert keeps everything but the plaintext, the same as the SQL versions
+func (m *MemoryTokenModel) Insert(ctx context.Context, token *Token) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored := *token
+	stored.Plaintext = ""
+	m.tokens[string(token.Hash)] = stored
+	return nil
+}
+
+func (m *MemoryTokenModel) DeleteAllForUser(ctx context.Context, scope string, userID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for hash, token := range m.tokens {
+		if token.Scope == scope && token.UserID == userID {
+			delete(m.tokens, hash)
+		}
+	}
+	return nil
+}
+
+// get looks a token up by its hash
+func (m *MemoryTokenModel) get(hash []byte) (Token, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token, ok := m.tokens[string(hash)]
+	return token, ok
+}
@@ -0,0 +1,33 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+)
+
+// SQLiteTokenModel stores tokens in SQLite
+type SQLiteTokenModel struct {
+	DB *sql.DB
+}
+
+func (t SQLiteTokenModel) Insert(ctx context.Context, token *Token) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	query := `
+	INSERT INTO tokens (hash, user_id, expiry, scope)
+	VALUES (?, ?, ?, ?)`
+
+	args := []interface{}{token.Hash, token.UserID, token.Expiry, token.Scope}
+
+	_, err := t.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (t SQLiteTokenModel) DeleteAllForUser(ctx context.Context, scope string, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	_, err := t.DB.ExecContext(ctx, `DELETE FROM tokens WHERE scope = ? AND user_id = ?`, scope, userID)
+	return err
+}
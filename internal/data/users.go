@@ -0,0 +1,222 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/lib/pq"
+	"golang.org/x/crypto/bcrypt"
+
+	"readinglist/internal/validator"
+)
+
+// User is a person with their own reading list
+// the password is never sent back to the client, so it has no json name
+type User struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Password  password  `json:"-"`
+	Version   int32     `json:"-"`
+}
+
+// AnonymousUser is the user on a request that didn't send an Authorization header
+var AnonymousUser = &User{}
+
+// IsAnonymous reports whether the user is the AnonymousUser
+func (u *User) IsAnonymous() bool {
+	return u == AnonymousUser
+}
+
+// password keeps the plaintext password (only while a user is being registered) next to its bcrypt hash
+// plaintext is a pointer so "no password was given" can be told apart from an empty password
+type password struct {
+	plaintext *string
+	hash      []byte
+}
+
+// Set hashes the plaintext password with bcrypt and keeps both
+func (p *password) Set(plaintextPassword string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(plaintextPassword), 12)
+	if err != nil {
+		return err
+	}
+
+	p.plaintext = &plaintextPassword
+	p.hash = hash
+
+	return nil
+}
+
+// Matches checks the plaintext password against the stored hash
+func (p *password) Matches(plaintextPassword string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword(p.hash, []byte(plaintextPassword))
+	if err != nil {
+		switch {
+		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+			return false, nil
+		default:
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// EmailRX is a regular expression for checking the format of an email address
+// it is the pattern recommended by the W3C for HTML5 email inputs
+var EmailRX = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+\\/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
+
+func ValidateEmail(v *validator.Validator, email string) {
+	v.Check(email != "", "email", "must be provided")
+	v.Check(validator.Matches(email, EmailRX), "email", "must be a valid email address")
+}
+
+// ValidatePasswordPlaintext checks the length of a password; bcrypt ignores anything after 72 bytes
+func ValidatePasswordPlaintext(v *validator.Validator, password string) {
+	v.Check(password != "", "password", "must be provided")
+	v.Check(len(password) >= 8, "password", "must be at least 8 bytes long")
+	v.Check(len(password) <= 72, "password", "must not be more than 72 bytes long")
+}
+
+func ValidateUser(v *validator.Validator, user *User) {
+	v.Check(user.Name != "", "name", "must be provided")
+	v.Check(len(user.Name) <= 500, "name", "must not be more than 500 bytes long")
+
+	ValidateEmail(v, user.Email)
+
+	if user.Password.plaintext != nil {
+		ValidatePasswordPlaintext(v, *user.Password.plaintext)
+	}
+
+	//a missing hash is a bug in the code that made the user rather than a problem with the request
+	if user.Password.hash == nil {
+		panic("missing password hash for user")
+	}
+}
+
+// UserStore is the set of operations for user accounts
+// it is implemented by UserModel (PostgreSQL), SQLiteUserModel and MemoryUserModel
+// Insert returns ErrDuplicate when the email address is already registered
+// it grants the user the permission codes in the same transaction, so a new user is never left without their permissions
+type UserStore interface {
+	Insert(ctx context.Context, user *User, permissions ...string) error
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	GetForToken(ctx context.Context, scope, tokenPlaintext string) (*User, error)
+}
+
+// UserModel stores users in PostgreSQL
+type UserModel struct {
+	DB *sql.DB
+}
+
+func (u UserModel) Insert(ctx context.Context, user *User, permissions ...string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	query := `
+	INSERT INTO users (name, email, password_hash)
+	VALUES ($1, $2, $3)
+	RETURNING id, created_at, version`
+
+	args := []interface{}{user.Name, user.Email, user.Password.hash}
+
+	tx, err := u.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.CreatedAt, &user.Version)
+	if err != nil {
+		//23505 is unique_violation, and email is the only unique column
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrDuplicate
+		}
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, addPermissionsQuery, user.ID, pq.Array(permissions)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetByEmail finds a user by email address; the citext column makes the match ignore case
+func (u UserModel) GetByEmail(ctx context.Context, email string) (*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	query := `
+	SELECT id, created_at, name, email, password_hash, version
+	FROM users
+	WHERE email = $1`
+
+	var user User
+
+	err := u.DB.QueryRowContext(ctx, query, email).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&user.Password.hash,
+		&user.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
+
+// GetForToken finds the user a token belongs to, as long as the token has the right scope and hasn't expired
+func (u UserModel) GetForToken(ctx context.Context, scope, tokenPlaintext string) (*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	query := `
+	SELECT users.id, users.created_at, users.name, users.email, users.password_hash, users.version
+	FROM users
+	INNER JOIN tokens ON users.id = tokens.user_id
+	WHERE tokens.hash = $1
+	AND tokens.scope = $2
+	AND tokens.expiry > $3`
+
+	//the array is turned into a slice because the driver can't use a [32]byte
+	args := []interface{}{tokenHash[:], scope, time.Now()}
+
+	var user User
+
+	err := u.DB.QueryRowContext(ctx, query, args...).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&user.Password.hash,
+		&user.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,86 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryUserModel keeps users in memory
+// it holds on to the token store so GetForToken can find the user a token belongs to,
+// and the permission store so Insert can grant a new user their permissions
+type MemoryUserModel struct {
+	mu          sync.Mutex
+	tokens      *MemoryTokenModel
+	permissions *MemoryPermissionModel
+	users       map[int64]User
+	nextID      int64
+}
+
+// NewMemoryUserModel returns an empty in-memory user store that looks tokens up in tokens and grants permissions in permissions
+func NewMemoryUserModel(tokens *MemoryTokenModel, permissions *MemoryPermissionModel) *MemoryUserModel {
+	return &MemoryUserModel{
+		tokens:      tokens,
+		permissions: permissions,
+		users:       make(map[int64]User),
+		nextID:      1,
+	}
+}
+
+// Insert returns ErrDuplicate if the email address is taken, ignoring case like the SQL versions
+// granting the permissions can't fail in memory, so there is nothing to roll back
+func (m *MemoryUserModel) Insert(ctx context.Context, user *User, permissions ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, existing := range m.users {
+		if strings.EqualFold(existing.Email, user.Email) {
+			return ErrDuplicate
+		}
+	}
+
+	user.ID = m.nextID
+	user.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	user.Version = 1
+	m.nextID++
+
+	stored := *user
+	stored.Password.plaintext = nil //the plaintext password is never kept
+	m.users[user.ID] = stored
+
+	return m.permissions.AddForUser(ctx, user.ID, permissions...)
+}
+
+func (m *MemoryUserModel) GetByEmail(ctx context.Context, email string) (*User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, user := range m.users {
+		if strings.EqualFold(user.Email, email) {
+			return &user, nil
+		}
+	}
+
+	return nil, ErrRecordNotFound
+}
+
+func (m *MemoryUserModel) GetForToken(ctx context.Context, scope, tokenPlaintext string) (*User, error) {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	token, ok := m.tokens.get(tokenHash[:])
+	if !ok || token.Scope != scope || !token.Expiry.After(time.Now()) {
+		return nil, ErrRecordNotFound
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[token.UserID]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+
+	return &user, nil
+}
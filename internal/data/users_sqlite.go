@@ -0,0 +1,114 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// SQLiteUserModel stores users in SQLite
+// the email column is COLLATE NOCASE, so lookups ignore case like the citext column in PostgreSQL
+type SQLiteUserModel struct {
+	DB *sql.DB
+}
+
+func (u SQLiteUserModel) Insert(ctx context.Context, user *User, permissions ...string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	user.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	user.Version = 1
+
+	query := `
+	INSERT INTO users (created_at, name, email, password_hash, version)
+	VALUES (?, ?, ?, ?, ?)`
+
+	args := []interface{}{user.CreatedAt, user.Name, user.Email, user.Password.hash, user.Version}
+
+	tx, err := u.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return ErrDuplicate
+		}
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, sqliteAddPermissionsQuery, id, jsonArray{&permissions}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	user.ID = id
+	return nil
+}
+
+func (u SQLiteUserModel) GetByEmail(ctx context.Context, email string) (*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	query := `
+	SELECT id, created_at, name, email, password_hash, version
+	FROM users
+	WHERE email = ?`
+
+	return u.get(ctx, query, email)
+}
+
+// GetForToken compares the expiry with the current time in UTC because SQLite compares the stored times as text
+func (u SQLiteUserModel) GetForToken(ctx context.Context, scope, tokenPlaintext string) (*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	query := `
+	SELECT users.id, users.created_at, users.name, users.email, users.password_hash, users.version
+	FROM users
+	INNER JOIN tokens ON users.id = tokens.user_id
+	WHERE tokens.hash = ?
+	AND tokens.scope = ?
+	AND tokens.expiry > ?`
+
+	return u.get(ctx, query, tokenHash[:], scope, time.Now().UTC().Truncate(time.Second))
+}
+
+// get runs a query that selects a single user
+func (u SQLiteUserModel) get(ctx context.Context, query string, args ...interface{}) (*User, error) {
+	var user User
+
+	err := u.DB.QueryRowContext(ctx, query, args...).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&user.Password.hash,
+		&user.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
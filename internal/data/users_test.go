@@ -0,0 +1,158 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPassword(t *testing.T) {
+	var p password
+
+	if err := p.Set("pa55word123"); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := p.Matches("pa55word123"); err != nil || !ok {
+		t.Errorf("Matches with the right password = %v, %v", ok, err)
+	}
+
+	if ok, err := p.Matches("wrong password"); err != nil || ok {
+		t.Errorf("Matches with the wrong password = %v, %v", ok, err)
+	}
+}
+
+func TestMemoryUserStore(t *testing.T) {
+	testUserStore(t, newMemoryModels)
+}
+
+func TestSQLiteUserStore(t *testing.T) {
+	testUserStore(t, newSQLiteModels)
+}
+
+func TestPostgresUserStore(t *testing.T) {
+	testUserStore(t, newPostgresModels)
+}
+
+func testUserStore(t *testing.T, newModels func(t *testing.T) Models) {
+	t.Run("InsertAndGetByEmail", func(t *testing.T) {
+		models := newModels(t)
+		user := seedUser(t, models.Users, "alice@example.com")
+
+		if user.ID < 1 || user.Version != 1 || user.CreatedAt.IsZero() {
+			t.Fatalf("Insert did not set id, version and created_at: %+v", user)
+		}
+
+		//email addresses are matched without caring about case
+		got, err := models.Users.GetByEmail(context.Background(), "Alice@Example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got.ID != user.ID || got.Email != "alice@example.com" || string(got.Password.hash) != string(user.Password.hash) {
+			t.Errorf("GetByEmail = %+v; want %+v", got, user)
+		}
+
+		if _, err := models.Users.GetByEmail(context.Background(), "nobody@example.com"); !errors.Is(err, ErrRecordNotFound) {
+			t.Errorf("GetByEmail for a missing user returned %v; want ErrRecordNotFound", err)
+		}
+
+		duplicate := &User{Name: "Someone Else", Email: "ALICE@example.com"}
+		duplicate.Password.hash = []byte("not a real hash")
+		if err := models.Users.Insert(context.Background(), duplicate); !errors.Is(err, ErrDuplicate) {
+			t.Errorf("Insert with a taken email returned %v; want ErrDuplicate", err)
+		}
+	})
+
+	t.Run("InsertWithPermissions", func(t *testing.T) {
+		models := newModels(t)
+
+		user := &User{Name: "Test User", Email: "alice@example.com"}
+		user.Password.hash = []byte("not a real hash")
+
+		if err := models.Users.Insert(context.Background(), user, DefaultPermissions...); err != nil {
+			t.Fatal(err)
+		}
+
+		permissions, err := models.Permissions.GetAllForUser(context.Background(), user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !equalStrings(permissions, DefaultPermissions) {
+			t.Errorf("permissions after Insert = %q; want %q", permissions, DefaultPermissions)
+		}
+	})
+
+	t.Run("Tokens", func(t *testing.T) {
+		models := newModels(t)
+		user := seedUser(t, models.Users, "alice@example.com")
+
+		token, err := GenerateToken(user.ID, time.Hour, ScopeAuthentication)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(token.Plaintext) != 26 {
+			t.Errorf("token %q is %d bytes long; want 26", token.Plaintext, len(token.Plaintext))
+		}
+
+		if err := models.Tokens.Insert(context.Background(), token); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := models.Users.GetForToken(context.Background(), ScopeAuthentication, token.Plaintext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ID != user.ID {
+			t.Errorf("GetForToken returned user %d; want %d", got.ID, user.ID)
+		}
+
+		if _, err := models.Users.GetForToken(context.Background(), "activation", token.Plaintext); !errors.Is(err, ErrRecordNotFound) {
+			t.Errorf("GetForToken with the wrong scope returned %v; want ErrRecordNotFound", err)
+		}
+
+		expired, err := GenerateToken(user.ID, -time.Hour, ScopeAuthentication)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := models.Tokens.Insert(context.Background(), expired); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := models.Users.GetForToken(context.Background(), ScopeAuthentication, expired.Plaintext); !errors.Is(err, ErrRecordNotFound) {
+			t.Errorf("GetForToken with an expired token returned %v; want ErrRecordNotFound", err)
+		}
+
+		if err := models.Tokens.DeleteAllForUser(context.Background(), ScopeAuthentication, user.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := models.Users.GetForToken(context.Background(), ScopeAuthentication, token.Plaintext); !errors.Is(err, ErrRecordNotFound) {
+			t.Errorf("GetForToken after DeleteAllForUser returned %v; want ErrRecordNotFound", err)
+		}
+	})
+}
+
+// TestSQLiteUserInsertRollsBack checks that a user whose permissions can't be granted isn't left behind
+// the users_permissions table is dropped so the second statement in Insert fails
+func TestSQLiteUserInsertRollsBack(t *testing.T) {
+	models := newSQLiteModels(t)
+	db := models.Users.(SQLiteUserModel).DB
+
+	if _, err := db.Exec(`DROP TABLE users_permissions`); err != nil {
+		t.Fatal(err)
+	}
+
+	user := &User{Name: "Test User", Email: "alice@example.com"}
+	user.Password.hash = []byte("not a real hash")
+
+	if err := models.Users.Insert(context.Background(), user, DefaultPermissions...); err == nil {
+		t.Fatal("Insert without a users_permissions table succeeded")
+	}
+
+	//the email address is still free because the user was rolled back with the permissions
+	if _, err := models.Users.GetByEmail(context.Background(), "alice@example.com"); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("GetByEmail after the failed Insert returned %v; want ErrRecordNotFound", err)
+	}
+}
@@ -0,0 +1,371 @@
+package jsonpatch
+
+//this package applies the two JSON patch formats a PATCH request can be sent in:
+//  application/merge-patch+json (RFC 7386), where the patch looks like the document and null removes a member
+//  application/json-patch+json (RFC 6902), where the patch is a list of add, remove, replace, move, copy and test operations
+//both work on the JSON encoding of a document and return the patched JSON, so the caller decodes the result the same way as any other body
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidPatch is returned when the patch itself is malformed, such as bad JSON or an unknown operation
+	ErrInvalidPatch = errors.New("invalid patch")
+	// ErrCannotApply is returned when a JSON Patch operation refers to a location the document doesn't have
+	ErrCannotApply = errors.New("patch cannot be applied")
+	// ErrTestFailed is returned when a JSON Patch test operation doesn't match the document
+	ErrTestFailed = errors.New("patch test failed")
+)
+
+// Merge applies a JSON Merge Patch to doc
+// objects in the patch are merged member by member, null removes a member, and anything else (arrays included) replaces the value
+func Merge(doc, patch []byte) ([]byte, error) {
+	var target, p any
+
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	return json.Marshal(merge(target, p))
+}
+
+func merge(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+
+	for key, value := range p {
+		if value == nil {
+			delete(t, key)
+		} else {
+			t[key] = merge(t[key], value)
+		}
+	}
+
+	return t
+}
+
+// Operation is one step of a JSON Patch
+// Value is raw so a missing value can be told apart from null
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Apply applies a JSON Patch to doc
+// the operations are applied in order and the whole patch fails if any one of them does, so doc is never half patched
+func Apply(doc, patch []byte) ([]byte, error) {
+	var target any
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+
+	//members an operation doesn't use, such as a value on a remove, are ignored as RFC 6902 section 4 asks
+	var ops []Operation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	for i, op := range ops {
+		var err error
+
+		target, err = apply(target, op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+
+	return json.Marshal(target)
+}
+
+func apply(doc any, op Operation) (any, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	//add, replace and test need a value; everything else ignores it
+	var value any
+	if op.Op == "add" || op.Op == "replace" || op.Op == "test" {
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: missing value", ErrInvalidPatch)
+		}
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+	}
+
+	switch op.Op {
+	case "add":
+		return add(doc, path, value)
+
+	case "remove":
+		doc, _, err := remove(doc, path)
+		return doc, err
+
+	case "replace":
+		if _, err := get(doc, path); err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return value, nil
+		}
+		doc, _, err := remove(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+
+		if op.Op == "move" && len(from) < len(path) && reflect.DeepEqual(from, path[:len(from)]) {
+			return nil, fmt.Errorf("%w: a value can't be moved into one of its own children", ErrCannotApply)
+		}
+
+		moved, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+
+		if op.Op == "move" {
+			doc, _, err = remove(doc, from)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			moved = deepCopy(moved)
+		}
+
+		return add(doc, path, moved)
+
+	case "test":
+		current, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(current, value) {
+			return nil, ErrTestFailed
+		}
+		return doc, nil
+
+	default:
+		return nil, fmt.Errorf("%w: unknown operation %q", ErrInvalidPatch, op.Op)
+	}
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) such as /genres/0 into its reference tokens
+// the empty pointer refers to the whole document
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: path %q must start with /", ErrInvalidPatch, pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		//~1 has to be replaced before ~0 so ~01 becomes ~1 and not /
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+// arrayIndex turns a token into an index into an array of length n
+// "-" means the end of the array, which is only allowed when adding
+func arrayIndex(token string, n int, adding bool) (int, error) {
+	if token == "-" && adding {
+		return n, nil
+	}
+
+	//leading zeros aren't allowed by RFC 6901
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrCannotApply, token)
+	}
+
+	i, err := strconv.Atoi(token)
+	max := n - 1
+	if adding {
+		max = n
+	}
+	if err != nil || i < 0 || i > max {
+		return 0, fmt.Errorf("%w: array index %q is out of range", ErrCannotApply, token)
+	}
+
+	return i, nil
+}
+
+// get returns the value at path
+func get(doc any, path []string) (any, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: member %q does not exist", ErrCannotApply, token)
+			}
+			doc = value
+
+		case []any:
+			i, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+
+		default:
+			return nil, fmt.Errorf("%w: %q is not inside an object or array", ErrCannotApply, token)
+		}
+	}
+
+	return doc, nil
+}
+
+// add puts value at path and returns the new document
+// adding to an object sets the member, and adding to an array inserts before the index, shifting the rest along
+func add(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	token, rest := path[0], path[1:]
+
+	switch node := doc.(type) {
+	case map[string]any:
+		if len(rest) == 0 {
+			node[token] = value
+			return node, nil
+		}
+
+		child, ok := node[token]
+		if !ok {
+			return nil, fmt.Errorf("%w: member %q does not exist", ErrCannotApply, token)
+		}
+
+		child, err := add(child, rest, value)
+		if err != nil {
+			return nil, err
+		}
+		node[token] = child
+		return node, nil
+
+	case []any:
+		i, err := arrayIndex(token, len(node), len(rest) == 0)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(rest) == 0 {
+			node = append(node, nil)
+			copy(node[i+1:], node[i:])
+			node[i] = value
+			return node, nil
+		}
+
+		child, err := add(node[i], rest, value)
+		if err != nil {
+			return nil, err
+		}
+		node[i] = child
+		return node, nil
+
+	default:
+		return nil, fmt.Errorf("%w: %q is not inside an object or array", ErrCannotApply, token)
+	}
+}
+
+// remove deletes the value at path and returns the new document along with the value that was removed
+func remove(doc any, path []string) (any, any, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("%w: the whole document can't be removed", ErrCannotApply)
+	}
+
+	token, rest := path[0], path[1:]
+
+	switch node := doc.(type) {
+	case map[string]any:
+		child, ok := node[token]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: member %q does not exist", ErrCannotApply, token)
+		}
+
+		if len(rest) == 0 {
+			delete(node, token)
+			return node, child, nil
+		}
+
+		child, removed, err := remove(child, rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		node[token] = child
+		return node, removed, nil
+
+	case []any:
+		i, err := arrayIndex(token, len(node), false)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if len(rest) == 0 {
+			removed := node[i]
+			return append(node[:i], node[i+1:]...), removed, nil
+		}
+
+		child, removed, err := remove(node[i], rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		node[i] = child
+		return node, removed, nil
+
+	default:
+		return nil, nil, fmt.Errorf("%w: %q is not inside an object or array", ErrCannotApply, token)
+	}
+}
+
+// deepCopy copies the maps and slices in a decoded JSON value so a copied value doesn't share them with the original
+func deepCopy(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		c := make(map[string]any, len(v))
+		for key, child := range v {
+			c[key] = deepCopy(child)
+		}
+		return c
+
+	case []any:
+		c := make([]any, len(v))
+		for i, child := range v {
+			c[i] = deepCopy(child)
+		}
+		return c
+
+	default:
+		return v
+	}
+}
@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+
+	requests := r.NewCounterVec("requests_total", "Requests handled.", "route", "code")
+	requests.Inc("GET /v1/books", "200")
+	requests.Inc("GET /v1/books", "200")
+	requests.Add(2.5, "GET /v1/books/{id}", "404")
+	//quotes, backslashes and newlines in label values have to be escaped
+	requests.Inc(`say "hi"\now`+"\n", "500")
+
+	inFlight := r.NewGauge("in_flight", "Requests in flight.\nHelp text can have newlines and \\ too.")
+	inFlight.Inc()
+	inFlight.Inc()
+	inFlight.Dec()
+
+	r.NewGaugeFunc("open_connections", "Open connections.", func() float64 { return 3 })
+
+	duration := r.NewHistogramVec("duration_seconds", "Request duration.", []float64{0.1, 0.5, 1}, "method")
+	//0.1 is on a bound, which counts as inside the bucket because the bounds are "less than or equal"
+	duration.Observe(0.05, "GET")
+	duration.Observe(0.1, "GET")
+	duration.Observe(0.3, "GET")
+	duration.Observe(2, "GET")
+	duration.Observe(0.7, "POST")
+
+	//the series are sorted by their label values joined with \xff, which sorts after any other character
+	want := `# HELP requests_total Requests handled.
+# TYPE requests_total counter
+requests_total{route="GET /v1/books/{id}",code="404"} 2.5
+requests_total{route="GET /v1/books",code="200"} 2
+requests_total{route="say \"hi\"\\now\n",code="500"} 1
+# HELP in_flight Requests in flight.\nHelp text can have newlines and \\ too.
+# TYPE in_flight gauge
+in_flight 1
+# HELP open_connections Open connections.
+# TYPE open_connections gauge
+open_connections 3
+# HELP duration_seconds Request duration.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{method="GET",le="0.1"} 2
+duration_seconds_bucket{method="GET",le="0.5"} 3
+duration_seconds_bucket{method="GET",le="1"} 3
+duration_seconds_bucket{method="GET",le="+Inf"} 4
+duration_seconds_sum{method="GET"} 2.45
+duration_seconds_count{method="GET"} 4
+duration_seconds_bucket{method="POST",le="0.1"} 0
+duration_seconds_bucket{method="POST",le="0.5"} 0
+duration_seconds_bucket{method="POST",le="1"} 1
+duration_seconds_bucket{method="POST",le="+Inf"} 1
+duration_seconds_sum{method="POST"} 0.7
+duration_seconds_count{method="POST"} 1
+`
+
+	var b strings.Builder
+	r.WriteText(&b)
+
+	if got := b.String(); got != want {
+		t.Errorf("output differs\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteTextEmpty(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("requests_total", "Requests handled.", "code")
+	r.NewHistogramVec("duration_seconds", "Request duration.", DefaultBuckets)
+
+	//a vector with no series yet only has its header, so the metric is still known to Prometheus
+	want := `# HELP requests_total Requests handled.
+# TYPE requests_total counter
+# HELP duration_seconds Request duration.
+# TYPE duration_seconds histogram
+`
+
+	var b strings.Builder
+	r.WriteText(&b)
+
+	if got := b.String(); got != want {
+		t.Errorf("output differs\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.NewGauge("up", "Whether the server is up.").Set(1)
+
+	rr := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if got, want := rr.Header().Get("Content-Type"), "text/plain; version=0.0.4; charset=utf-8"; got != want {
+		t.Errorf("Content-Type = %q; want %q", got, want)
+	}
+
+	if !strings.Contains(rr.Body.String(), "\nup 1\n") {
+		t.Errorf("body = %q; want the up gauge", rr.Body.String())
+	}
+}
+
+func TestWrongLabelCount(t *testing.T) {
+	c := NewRegistry().NewCounterVec("requests_total", "Requests handled.", "route", "code")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Inc with the wrong number of label values didn't panic")
+		}
+	}()
+
+	c.Inc("GET /v1/books")
+}
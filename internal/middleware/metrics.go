@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"readinglist/internal/metrics"
+)
+
+// RequestMetrics are the request metrics both servers serve at /metrics
+type RequestMetrics struct {
+	requests *metrics.CounterVec
+	duration *metrics.HistogramVec
+	inFlight *metrics.Gauge
+}
+
+// NewRequestMetrics adds the request metrics to registry
+func NewRequestMetrics(registry *metrics.Registry) *RequestMetrics {
+	return &RequestMetrics{
+		requests: registry.NewCounterVec("http_requests_total", "Number of HTTP requests handled, by route, method and status code.", "route", "method", "code"),
+		duration: registry.NewHistogramVec("http_request_duration_seconds", "How long HTTP requests took to handle, by route and method.", metrics.DefaultBuckets, "route", "method"),
+		inFlight: registry.NewGauge("http_requests_in_flight", "Number of HTTP requests being handled right now."),
+	}
+}
+
+// RecordMetrics counts every request and times it
+// the route label is the mux pattern the request matched, such as GET /v1/books/{id}, so ids in the path or the query string
+// don't make a new series for every book; a request that matches nothing is counted as "unmatched"
+func RecordMetrics(m *RequestMetrics, mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, route := mux.Handler(r)
+		if route == "" {
+			route = "unmatched"
+		}
+
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
+
+		start := time.Now()
+		rec := NewStatusRecorder(w)
+
+		next.ServeHTTP(rec, r)
+
+		m.requests.Inc(route, r.Method, strconv.Itoa(rec.Status()))
+		m.duration.Observe(time.Since(start).Seconds(), route, r.Method)
+	})
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"readinglist/internal/metrics"
+)
+
+func TestRecordMetrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	m := NewRequestMetrics(registry)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/books/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	handler := RecordMetrics(m, mux, mux)
+	for _, path := range []string{"/v1/books/1", "/v1/books/2", "/nothing"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	var b strings.Builder
+	registry.WriteText(&b)
+	out := b.String()
+
+	//both books share the route of the pattern rather than getting a series each
+	for _, want := range []string{
+		`http_requests_total{route="GET /v1/books/{id}",method="GET",code="418"} 2`,
+		`http_requests_total{route="unmatched",method="GET",code="404"} 1`,
+		`http_request_duration_seconds_count{route="GET /v1/books/{id}",method="GET"} 2`,
+		"http_requests_in_flight 0",
+	} {
+		if !strings.Contains(out, want+"\n") {
+			t.Errorf("metrics are missing %s\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package middleware
+
+//this package holds the middleware the api and the web app share, the same way internal/requestid holds the request ids they share
+//each one is given the logger to write to, so both servers log the same way under their own settings
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"readinglist/internal/requestid"
+)
+
+// StatusRecorder wraps a ResponseWriter to remember the status code and how many bytes of body were written
+type StatusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+// NewStatusRecorder wraps w
+func NewStatusRecorder(w http.ResponseWriter) *StatusRecorder {
+	return &StatusRecorder{ResponseWriter: w}
+}
+
+func (rec *StatusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *StatusRecorder) Write(b []byte) (int, error) {
+	//a handler that writes without calling WriteHeader sends a 200
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the real ResponseWriter underneath
+func (rec *StatusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// Status is the status code that was sent; a handler that never writes anything still sends a 200
+func (rec *StatusRecorder) Status() int {
+	if rec.status == 0 {
+		return http.StatusOK
+	}
+	return rec.status
+}
+
+// Bytes is how many bytes of body were written
+func (rec *StatusRecorder) Bytes() int {
+	return rec.bytes
+}
+
+// LogRequest logs one line for every request once the response has been sent
+// it goes just inside the middleware that sets the request id so the status and duration include everything the other middleware did
+func LogRequest(logger *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := NewStatusRecorder(w)
+
+		next.ServeHTTP(rec, r)
+
+		logger.Info("request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.Status(),
+			"duration", time.Since(start).String(),
+			"bytes", rec.Bytes(),
+			"request_id", requestid.FromContext(r.Context()),
+		)
+	})
+}
+
+// RecoverPanic turns a panic in a handler into a 500 instead of a dropped connection
+// the panic is logged with its stack and then respond sends the 500 in the server's own way, such as a JSON error or an error page
+// it goes inside LogRequest so the request is still logged, with its 500, after the panic
+func RecoverPanic(logger *slog.Logger, respond func(w http.ResponseWriter, r *http.Request), next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if err := recover(); err != nil {
+				//http.ErrAbortHandler is how a handler deliberately gives up on a response, so it is left for net/http to deal with
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+
+				//the handler may have left the connection in a bad state, so net/http is told to close it after this response
+				w.Header().Set("Connection", "close")
+
+				logger.Error("panic", "method", r.Method, "uri", r.URL.RequestURI(), "request_id", requestid.FromContext(r.Context()), "error", fmt.Sprint(err), "stack", string(debug.Stack()))
+
+				respond(w, r)
+			}
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"readinglist/internal/requestid"
+)
+
+func TestStatusRecorder(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		wantStatus int
+		wantBytes  int
+	}{
+		{
+			name:       "nothing written",
+			handler:    func(w http.ResponseWriter, r *http.Request) {},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "body without WriteHeader",
+			handler:    func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("hello")) },
+			wantStatus: http.StatusOK,
+			wantBytes:  5,
+		},
+		{
+			name: "status and body",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte("not here"))
+			},
+			wantStatus: http.StatusNotFound,
+			wantBytes:  8,
+		},
+		{
+			//net/http only sends the first status, so that is the one that is kept
+			name: "WriteHeader twice",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			wantStatus: http.StatusCreated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			rec := NewStatusRecorder(rr)
+
+			tt.handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if rec.Status() != tt.wantStatus || rec.Bytes() != tt.wantBytes {
+				t.Errorf("Status() = %d, Bytes() = %d; want %d and %d", rec.Status(), rec.Bytes(), tt.wantStatus, tt.wantBytes)
+			}
+			if rec.Unwrap() != rr {
+				t.Error("Unwrap didn't return the ResponseWriter underneath")
+			}
+		})
+	}
+}
+
+func TestLogRequest(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("short and stout"))
+	})
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/books?page=2", nil)
+	r = r.WithContext(requestid.NewContext(r.Context(), "abc123"))
+
+	LogRequest(logger, next).ServeHTTP(httptest.NewRecorder(), r)
+
+	var line struct {
+		Msg       string `json:"msg"`
+		Method    string `json:"method"`
+		Path      string `json:"path"`
+		Status    int    `json:"status"`
+		Bytes     int    `json:"bytes"`
+		Duration  string `json:"duration"`
+		RequestID string `json:"request_id"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("the log line %q isn't JSON: %v", buf.String(), err)
+	}
+
+	if line.Msg != "request" || line.Method != http.MethodPost || line.Path != "/v1/books" || line.Status != http.StatusTeapot ||
+		line.Bytes != 15 || line.Duration == "" || line.RequestID != "abc123" {
+		t.Errorf("log line = %+v", line)
+	}
+}
+
+func TestRecoverPanic(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("something broke")
+	})
+
+	respond := func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "sorry", http.StatusInternalServerError)
+	}
+
+	rr := httptest.NewRecorder()
+	RecoverPanic(logger, respond, next).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rr.Code != http.StatusInternalServerError || rr.Header().Get("Connection") != "close" {
+		t.Errorf("status = %d, Connection = %q; want 500 and close", rr.Code, rr.Header().Get("Connection"))
+	}
+
+	var line struct {
+		Msg   string `json:"msg"`
+		Error string `json:"error"`
+		Stack string `json:"stack"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatal(err)
+	}
+	if line.Msg != "panic" || line.Error != "something broke" || line.Stack == "" {
+		t.Errorf("log line = %+v; want the panic with its stack", line)
+	}
+}
+
+func TestRecoverPanicAbortHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	})
+
+	respond := func(w http.ResponseWriter, r *http.Request) {
+		t.Error("respond was called for http.ErrAbortHandler")
+	}
+
+	defer func() {
+		err, _ := recover().(error)
+		if !errors.Is(err, http.ErrAbortHandler) {
+			t.Errorf("recovered %v; want http.ErrAbortHandler to be panicked again", err)
+		}
+	}()
+
+	RecoverPanic(logger, respond, next).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,281 @@
+package migrate
+
+//this package applies the numbered schema migrations that are embedded in the binary
+//each migration is a pair of files like 000001_create_books_table.up.sql and 000001_create_books_table.down.sql
+//there is one folder of migrations per database driver because PostgreSQL and SQLite don't share column types
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations
+var files embed.FS
+
+// the file names have to look like 000001_some_description.up.sql
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is one numbered change to the schema along with the SQL to undo it
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status describes one migration and whether it has been applied to the database
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator applies migrations to a database and records them in the schema_migrations table
+type Migrator struct {
+	DB         *sql.DB
+	driver     string
+	migrations []Migration //sorted by version, oldest first
+}
+
+// New loads the migrations for the driver ("postgres" or "sqlite") and creates the schema_migrations table if needed
+func New(db *sql.DB, driver string) (*Migrator, error) {
+	migrations, err := load(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Migrator{DB: db, driver: driver, migrations: migrations}
+
+	query := `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version bigint PRIMARY KEY,
+		applied_at timestamp NOT NULL
+	)`
+
+	if _, err := db.Exec(query); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// load reads the embedded migration files for a driver and pairs up the up and down files
+func load(driver string) ([]Migration, error) {
+	dir := path.Join("migrations", driver)
+
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for driver %q", driver)
+	}
+
+	byVersion := map[int]*Migration{}
+
+	for _, entry := range entries {
+		matches := fileName.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			return nil, fmt.Errorf("badly named migration file %q", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(matches[1])
+
+		contents, err := fs.ReadFile(files, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = migration
+		}
+
+		if matches[3] == "up" {
+			migration.Up = string(contents)
+		} else {
+			migration.Down = string(contents)
+		}
+	}
+
+	migrations := []Migration{}
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d is missing its up or down file", migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// bind swaps the PostgreSQL placeholders for the ones SQLite expects
+func (m *Migrator) bind(query string) string {
+	if m.driver == "sqlite" {
+		return strings.NewReplacer("$1", "?", "$2", "?").Replace(query)
+	}
+	return query
+}
+
+// Latest returns the newest version the binary knows about
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version returns the newest migration that has been applied, or 0 if the database is empty
+func (m *Migrator) Version() (int, error) {
+	return m.VersionContext(context.Background())
+}
+
+// VersionContext is Version with a context, so a health check can give up on a database that doesn't answer
+func (m *Migrator) VersionContext(ctx context.Context) (int, error) {
+	var version sql.NullInt64
+
+	err := m.DB.QueryRowContext(ctx, `SELECT max(version) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(version.Int64), nil
+}
+
+// Status lists every migration and when it was applied
+func (m *Migrator) Status() ([]Status, error) {
+	rows, err := m.DB.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := []Status{}
+	for _, migration := range m.migrations {
+		appliedAt, ok := applied[migration.Version]
+		statuses = append(statuses, Status{Migration: migration, Applied: ok, AppliedAt: appliedAt})
+	}
+
+	return statuses, nil
+}
+
+// Up applies every migration that hasn't been applied yet
+func (m *Migrator) Up() error {
+	return m.Goto(m.Latest())
+}
+
+// Down rolls back the newest applied migration
+func (m *Migrator) Down() error {
+	current, err := m.Version()
+	if err != nil {
+		return err
+	}
+
+	if current == 0 {
+		return errors.New("no migrations to roll back")
+	}
+
+	//the target is the version before the current one, or 0 to roll back everything
+	target := 0
+	for _, migration := range m.migrations {
+		if migration.Version < current {
+			target = migration.Version
+		}
+	}
+
+	return m.Goto(target)
+}
+
+// Goto migrates up or down until the database is at the given version
+// each migration runs in its own transaction together with its schema_migrations row
+// so a failed migration leaves the database at the last version that worked
+func (m *Migrator) Goto(version int) error {
+	if version != 0 && !m.known(version) {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+
+	current, err := m.Version()
+	if err != nil {
+		return err
+	}
+
+	if version > current {
+		for _, migration := range m.migrations {
+			if migration.Version > current && migration.Version <= version {
+				err := m.apply(migration.Up, `INSERT INTO schema_migrations (version, applied_at) VALUES ($1, $2)`, migration.Version, time.Now().UTC())
+				if err != nil {
+					return fmt.Errorf("migration %d (%s) up: %w", migration.Version, migration.Name, err)
+				}
+			}
+		}
+	}
+
+	if version < current {
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if migration.Version <= current && migration.Version > version {
+				err := m.apply(migration.Down, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+				if err != nil {
+					return fmt.Errorf("migration %d (%s) down: %w", migration.Version, migration.Name, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *Migrator) known(version int) bool {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// apply runs the migration SQL and the schema_migrations change in one transaction
+func (m *Migrator) apply(migrationSQL string, trackingSQL string, args ...any) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //this does nothing once the transaction has been committed
+
+	if _, err := tx.Exec(migrationSQL); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(m.bind(trackingSQL), args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
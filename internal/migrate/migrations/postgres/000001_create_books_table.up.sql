@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS books (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    title text NOT NULL,
+    published integer NOT NULL,
+    pages integer NOT NULL,
+    genres text[] NOT NULL,
+    rating real NOT NULL,
+    version integer NOT NULL DEFAULT 1
+);
@@ -0,0 +1,2 @@
+/* this index makes the full-text search on titles fast; the expression must match the one used in BookModel.Search */
+CREATE INDEX IF NOT EXISTS books_title_idx ON books USING GIN (to_tsvector('english', title));
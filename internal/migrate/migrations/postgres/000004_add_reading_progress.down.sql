@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS reading_sessions;
+ALTER TABLE books DROP COLUMN IF EXISTS finished_at;
+ALTER TABLE books DROP COLUMN IF EXISTS started_at;
+ALTER TABLE books DROP COLUMN IF EXISTS current_page;
+ALTER TABLE books DROP COLUMN IF EXISTS status;
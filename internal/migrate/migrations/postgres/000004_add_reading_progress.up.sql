@@ -0,0 +1,17 @@
+ALTER TABLE books ADD COLUMN IF NOT EXISTS status text NOT NULL DEFAULT 'want_to_read'
+    CHECK (status IN ('want_to_read', 'reading', 'finished', 'abandoned'));
+ALTER TABLE books ADD COLUMN IF NOT EXISTS current_page integer NOT NULL DEFAULT 0 CHECK (current_page >= 0);
+ALTER TABLE books ADD COLUMN IF NOT EXISTS started_at date;
+ALTER TABLE books ADD COLUMN IF NOT EXISTS finished_at date;
+
+/* each row is one sitting with a book, logged through POST /v1/books/{id}/progress */
+CREATE TABLE IF NOT EXISTS reading_sessions (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    book_id bigint NOT NULL REFERENCES books ON DELETE CASCADE,
+    pages_read integer NOT NULL CHECK (pages_read >= 0),
+    minutes integer NOT NULL CHECK (minutes >= 0),
+    read_on date NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS reading_sessions_book_id_idx ON reading_sessions (book_id);
@@ -0,0 +1,25 @@
+/* citext makes the unique email check ignore case, so Alice@example.com and alice@example.com are the same account */
+CREATE EXTENSION IF NOT EXISTS citext;
+
+CREATE TABLE IF NOT EXISTS users (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    name text NOT NULL,
+    email citext UNIQUE NOT NULL,
+    password_hash bytea NOT NULL,
+    version integer NOT NULL DEFAULT 1
+);
+
+/* only the SHA-256 hash of each token is stored, so a copy of the table can't be used to log in */
+CREATE TABLE IF NOT EXISTS tokens (
+    hash bytea PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    expiry timestamp(0) with time zone NOT NULL,
+    scope text NOT NULL
+);
+
+/* books that were added before there were users have no owner and won't show up for anyone
+   give them to an account with UPDATE books SET user_id = <id> WHERE user_id IS NULL */
+ALTER TABLE books ADD COLUMN IF NOT EXISTS user_id bigint REFERENCES users ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS books_user_id_idx ON books (user_id);
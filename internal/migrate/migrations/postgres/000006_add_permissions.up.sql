@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS permissions (
+    id bigserial PRIMARY KEY,
+    code text NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS users_permissions (
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    permission_id bigint NOT NULL REFERENCES permissions ON DELETE CASCADE,
+    PRIMARY KEY (user_id, permission_id)
+);
+
+INSERT INTO permissions (code)
+VALUES ('books:read'), ('books:write')
+ON CONFLICT (code) DO NOTHING;
+
+/* the users that already exist keep being able to read and change their own books */
+INSERT INTO users_permissions (user_id, permission_id)
+SELECT users.id, permissions.id FROM users CROSS JOIN permissions
+ON CONFLICT DO NOTHING;
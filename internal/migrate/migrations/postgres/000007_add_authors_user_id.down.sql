@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS authors_user_id_idx;
+ALTER TABLE authors DROP COLUMN IF EXISTS user_id;
@@ -0,0 +1,16 @@
+/* authors belong to the user who added them, like books do, so nobody can rename or delete an author on someone else's books */
+ALTER TABLE authors ADD COLUMN IF NOT EXISTS user_id bigint REFERENCES users ON DELETE CASCADE;
+
+/* an author who is already on some books goes to the owner of the oldest of them
+   authors who aren't on any book have no owner and won't show up for anyone;
+   give them to an account with UPDATE authors SET user_id = <id> WHERE user_id IS NULL */
+UPDATE authors SET user_id = (
+    SELECT books.user_id FROM book_authors
+    JOIN books ON books.id = book_authors.book_id
+    WHERE book_authors.author_id = authors.id AND books.user_id IS NOT NULL
+    ORDER BY books.id
+    LIMIT 1
+)
+WHERE user_id IS NULL;
+
+CREATE INDEX IF NOT EXISTS authors_user_id_idx ON authors (user_id);
@@ -0,0 +1,11 @@
+/* SQLite doesn't have array columns, so the genres are stored as a JSON array in a text column */
+CREATE TABLE IF NOT EXISTS books (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    title TEXT NOT NULL,
+    published INTEGER NOT NULL,
+    pages INTEGER NOT NULL,
+    genres TEXT NOT NULL DEFAULT '[]',
+    rating REAL NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1
+);
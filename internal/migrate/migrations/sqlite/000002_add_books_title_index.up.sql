@@ -0,0 +1,2 @@
+/* SQLite has no GIN indexes; a plain index on title keeps the numbering in step with the postgres migrations */
+CREATE INDEX IF NOT EXISTS books_title_idx ON books (title);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS authors (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    name TEXT NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1
+);
+
+/* the join table links books to authors; a person can have more than one role on the same book */
+CREATE TABLE IF NOT EXISTS book_authors (
+    book_id INTEGER NOT NULL REFERENCES books ON DELETE CASCADE,
+    author_id INTEGER NOT NULL REFERENCES authors ON DELETE CASCADE,
+    role TEXT NOT NULL CHECK (role IN ('author', 'translator', 'editor')),
+    PRIMARY KEY (book_id, author_id, role)
+);
+
+CREATE INDEX IF NOT EXISTS book_authors_author_id_idx ON book_authors (author_id);
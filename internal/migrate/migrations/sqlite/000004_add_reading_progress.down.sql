@@ -0,0 +1,6 @@
+/* DROP COLUMN needs SQLite 3.35 or newer */
+DROP TABLE IF EXISTS reading_sessions;
+ALTER TABLE books DROP COLUMN finished_at;
+ALTER TABLE books DROP COLUMN started_at;
+ALTER TABLE books DROP COLUMN current_page;
+ALTER TABLE books DROP COLUMN status;
@@ -0,0 +1,18 @@
+/* SQLite only allows one column per ALTER TABLE and has no IF NOT EXISTS for columns */
+ALTER TABLE books ADD COLUMN status TEXT NOT NULL DEFAULT 'want_to_read'
+    CHECK (status IN ('want_to_read', 'reading', 'finished', 'abandoned'));
+ALTER TABLE books ADD COLUMN current_page INTEGER NOT NULL DEFAULT 0 CHECK (current_page >= 0);
+ALTER TABLE books ADD COLUMN started_at DATE;
+ALTER TABLE books ADD COLUMN finished_at DATE;
+
+/* each row is one sitting with a book, logged through POST /v1/books/{id}/progress */
+CREATE TABLE IF NOT EXISTS reading_sessions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    book_id INTEGER NOT NULL REFERENCES books ON DELETE CASCADE,
+    pages_read INTEGER NOT NULL CHECK (pages_read >= 0),
+    minutes INTEGER NOT NULL CHECK (minutes >= 0),
+    read_on DATE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS reading_sessions_book_id_idx ON reading_sessions (book_id);
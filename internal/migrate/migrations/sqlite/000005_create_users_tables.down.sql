@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS books_user_id_idx;
+ALTER TABLE books DROP COLUMN user_id;
+DROP TABLE IF EXISTS tokens;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,23 @@
+/* COLLATE NOCASE does the job of PostgreSQL's citext so the unique email check ignores case */
+CREATE TABLE IF NOT EXISTS users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    name TEXT NOT NULL,
+    email TEXT NOT NULL UNIQUE COLLATE NOCASE,
+    password_hash BLOB NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1
+);
+
+/* only the SHA-256 hash of each token is stored, so a copy of the table can't be used to log in */
+CREATE TABLE IF NOT EXISTS tokens (
+    hash BLOB PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users ON DELETE CASCADE,
+    expiry DATETIME NOT NULL,
+    scope TEXT NOT NULL
+);
+
+/* books that were added before there were users have no owner and won't show up for anyone
+   give them to an account with UPDATE books SET user_id = <id> WHERE user_id IS NULL */
+ALTER TABLE books ADD COLUMN user_id INTEGER REFERENCES users ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS books_user_id_idx ON books (user_id);
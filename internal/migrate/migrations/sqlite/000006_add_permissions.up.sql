@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS permissions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    code TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS users_permissions (
+    user_id INTEGER NOT NULL REFERENCES users ON DELETE CASCADE,
+    permission_id INTEGER NOT NULL REFERENCES permissions ON DELETE CASCADE,
+    PRIMARY KEY (user_id, permission_id)
+);
+
+INSERT OR IGNORE INTO permissions (code)
+VALUES ('books:read'), ('books:write');
+
+/* the users that already exist keep being able to read and change their own books */
+INSERT OR IGNORE INTO users_permissions (user_id, permission_id)
+SELECT users.id, permissions.id FROM users CROSS JOIN permissions;
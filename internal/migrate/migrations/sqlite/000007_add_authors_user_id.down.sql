@@ -0,0 +1,3 @@
+/* DROP COLUMN needs SQLite 3.35 or newer */
+DROP INDEX IF EXISTS authors_user_id_idx;
+ALTER TABLE authors DROP COLUMN user_id;
@@ -1,73 +1,193 @@
 package models
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"readinglist/internal/requestid"
 )
 
-// the 3 types below allow us to unmarshall json
+// the types below allow us to unmarshall json
 type Book struct { //type for each book in the envelopes
-	ID        int64    `json:"id"`
-	Title     string   `json:"title"`
-	Published int      `json:"published"`
-	Pages     int      `json:"pages"`
-	Genres    []string `json:"genres"`
-	Rating    float32  `json:"rating"`
+	ID        int64        `json:"id"`
+	Title     string       `json:"title"`
+	Published int          `json:"published"`
+	Pages     int          `json:"pages"`
+	Genres    []string     `json:"genres"`
+	Rating    float32      `json:"rating"`
+	Authors   []BookAuthor `json:"authors"`
+	//the reading status and progress; the dates are nil until the book is started or finished
+	Status      string     `json:"status"`
+	CurrentPage int        `json:"current_page"`
+	StartedAt   *time.Time `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at"`
+	//ETag is the version of the book the web service sent in the ETag header
+	//Update and Delete send it back in If-Match so they fail if someone else changed the book in the meantime
+	ETag string `json:"-"`
+}
+
+// StatusLabel turns the status into something that can be shown on a page, for example want_to_read becomes "Want to read"
+func (b Book) StatusLabel() string {
+	label := strings.ReplaceAll(b.Status, "_", " ")
+	if label == "" {
+		return ""
+	}
+	return strings.ToUpper(label[:1]) + label[1:]
+}
+
+// PercentRead is how far through the book the reader is, rounded down to a whole percent
+func (b Book) PercentRead() int {
+	if b.Pages <= 0 {
+		return 0
+	}
+	return b.CurrentPage * 100 / b.Pages
+}
+
+type BookAuthor struct { //type for an author as they appear on a book
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+type Author struct { //type for a single author
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type AuthorResponse struct { //type for enveloped single-author json responses
+	Author *Author `json:"author"`
 }
 
 type BookResponse struct { //type for enveloped single-book json responses
 	Book *Book `json:"book"` //pointer to the book
 }
 
+type Metadata struct { //type for the paging information that comes back next to a list of books
+	CurrentPage  int `json:"current_page"`
+	PageSize     int `json:"page_size"`
+	FirstPage    int `json:"first_page"`
+	LastPage     int `json:"last_page"`
+	TotalRecords int `json:"total_records"`
+}
+
 type BooksResponse struct { //type for enveloped multi-book json responses
-	Books *[]Book `json:"books"` //pointer to a slice of books
+	Books    *[]Book  `json:"books"`    //pointer to a slice of books
+	Metadata Metadata `json:"metadata"` //the paging information for the books
 }
 
 type ReadinglistModel struct { //this type is what all of the methods "hang on to"
 	Endpoint string //this is the url to the web service
+	Token    string //this is the bearer token sent with every request; the web service only shows the books of the user it belongs to
+	//Client sends the requests; http.DefaultClient is used when it is nil
+	//setting it lets the caller wrap the transport, for example to time every call to the web service
+	Client *http.Client
+}
+
+// client returns the http.Client the requests are sent with
+func (m *ReadinglistModel) client() *http.Client {
+	if m.Client != nil {
+		return m.Client
+	}
+	return http.DefaultClient
+}
+
+// Do sends a request to the web service with the bearer token added
+// every request to the web service goes through here so none of them can forget the token
+// the request id in the request's context is forwarded too, so the api logs the call under the same id as the page
+func (m *ReadinglistModel) Do(req *http.Request) (*http.Response, error) {
+	if m.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+m.Token)
+	}
+
+	if id := requestid.FromContext(req.Context()); id != "" {
+		req.Header.Set(requestid.Header, id)
+	}
+
+	return m.client().Do(req)
 }
 
-// the method below returns all of the book records in the database for the homepage
-// it doesn't take any values but it returns a slice of books and an error
-func (m *ReadinglistModel) GetAll() (*[]Book, error) { //it is a method that hangs off of the dereferenced pointer to ReadinglistModel
-	resp, err := http.Get(m.Endpoint) //this is what passes the url into the web service
+// get sends a GET request for the url through Do
+// ctx is the context of the page's request, so the call is cancelled if the browser goes away
+func (m *ReadinglistModel) get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
+
+	return m.Do(req)
+}
+
+// the method below returns one page of book records for the homepage
+// it takes in the query string values (title, genres, page, page_size and sort) and passes them on to the web service
+// it returns a slice of books, the paging metadata and an error
+func (m *ReadinglistModel) GetAll(ctx context.Context, query url.Values) (*[]Book, *Metadata, error) { //it is a method that hangs off of the dereferenced pointer to ReadinglistModel
+	endpoint := m.Endpoint
+	if len(query) > 0 {
+		endpoint = fmt.Sprintf("%s?%s", m.Endpoint, query.Encode()) //this adds the query string onto the url
+	}
+
+	resp, err := m.get(ctx, endpoint) //this is what passes the url into the web service
+	if err != nil {
+		return nil, nil, err
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+		return nil, nil, fmt.Errorf("unexpected status: %s", resp.Status)
 	}
 
 	data, err := io.ReadAll(resp.Body) //this reads the response body and puts it into a variable called data
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	var booksResp BooksResponse //this handles the envelope
 
 	err = json.Unmarshal(data, &booksResp) //this unmarshalls the response in the data variable and puts it into the booksResp variable
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	return booksResp.Books, &booksResp.Metadata, nil //this returns the specific books data inside the envelope (does not return the envelope)
+}
+
+// the method below runs a full-text search on the book titles through the web service
+// the results come back ordered by how well they match the search terms
+// the query values are used for paging (page and page_size)
+func (m *ReadinglistModel) Search(ctx context.Context, q string, query url.Values) (*[]Book, *Metadata, error) {
+	searchQuery := url.Values{}
+	for key, values := range query { //this copies the query so the caller's values aren't changed
+		searchQuery[key] = values
 	}
+	searchQuery.Set("q", q)
 
-	return booksResp.Books, nil //this returns the specific books data inside the envelope (does not return the envelope)
+	return m.GetAll(ctx, searchQuery)
 }
 
 // this method takes in an id and returns a pointer to a book and an error - it returns a specific book by id
-func (m *ReadinglistModel) Get(id int64) (*Book, error) {
+// the error is ErrRecordNotFound when the user has no book with that id
+func (m *ReadinglistModel) Get(ctx context.Context, id int64) (*Book, error) {
 	url := fmt.Sprintf("%s/%d", m.Endpoint, id) //this makes the url variable contain a string with the endpoint and the id; it formats it fit the url style
-	resp, err := http.Get(url)
+	resp, err := m.get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrRecordNotFound
+	default:
 		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
 	}
 
@@ -83,5 +203,191 @@ func (m *ReadinglistModel) Get(id int64) (*Book, error) {
 		return nil, err
 	}
 
+	bookResp.Book.ETag = resp.Header.Get("ETag")
+
 	return bookResp.Book, nil //this returns the singular book without the envelope
 }
+
+// ErrEditConflict is returned by Update and Delete when the book was changed by someone else after it was read, so nothing was changed
+var ErrEditConflict = errors.New("models: the book was changed after it was read")
+
+// ErrRecordNotFound is returned when the web service answers 404, because the book or author doesn't exist or isn't on the user's list
+var ErrRecordNotFound = errors.New("models: record not found")
+
+// ValidationErrors is returned by Update when the web service rejects the book; it maps each field to what is wrong with it
+type ValidationErrors map[string]string
+
+func (e ValidationErrors) Error() string {
+	return fmt.Sprintf("models: the web service rejected %d fields", len(e))
+}
+
+// BookInput is everything about a book that can be changed
+// the web service replaces the whole book on a PUT, so Update always sends every field
+type BookInput struct {
+	Title       string       `json:"title"`
+	Published   int          `json:"published"`
+	Pages       int          `json:"pages"`
+	Genres      []string     `json:"genres"`
+	Rating      float32      `json:"rating"`
+	Status      string       `json:"status"`
+	CurrentPage int          `json:"current_page"`
+	Authors     []BookAuthor `json:"authors"`
+}
+
+// this method replaces the book with the input and returns the book as the web service saved it
+// etag is the ETag of the book when it was read; if the book has changed since then ErrEditConflict is returned
+// an empty etag sends no If-Match, which replaces the book whatever has happened to it, so the web app never sends one
+func (m *ReadinglistModel) Update(ctx context.Context, id int64, etag string, input BookInput) (*Book, error) {
+	//the web service treats a missing list as a missing field, so an empty list is sent instead of null
+	if input.Genres == nil {
+		input.Genres = []string{}
+	}
+	if input.Authors == nil {
+		input.Authors = []BookAuthor{}
+	}
+
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, fmt.Sprintf("%s/%d", m.Endpoint, id), bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if etag != "" {
+		req.Header.Set("If-Match", etag)
+	}
+
+	resp, err := m.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+
+	case http.StatusNotFound:
+		return nil, ErrRecordNotFound
+
+	//412 means the If-Match didn't match; 409 means another change was saved between the web service reading the book and writing it
+	case http.StatusPreconditionFailed, http.StatusConflict:
+		return nil, ErrEditConflict
+
+	case http.StatusUnprocessableEntity:
+		var errResp struct {
+			Error ValidationErrors `json:"error"`
+		}
+
+		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
+			return nil, err
+		}
+
+		return nil, errResp.Error
+
+	default:
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	var bookResp BookResponse
+
+	if err := json.NewDecoder(resp.Body).Decode(&bookResp); err != nil {
+		return nil, err
+	}
+
+	bookResp.Book.ETag = resp.Header.Get("ETag")
+
+	return bookResp.Book, nil
+}
+
+// this method deletes the book with the id
+// etag is the ETag of the book when it was read; if the book has changed since then ErrEditConflict is returned and it isn't deleted
+// like Update, an empty etag deletes the book whatever has happened to it
+func (m *ReadinglistModel) Delete(ctx context.Context, id int64, etag string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, fmt.Sprintf("%s/%d", m.Endpoint, id), nil)
+	if err != nil {
+		return err
+	}
+
+	if etag != "" {
+		req.Header.Set("If-Match", etag)
+	}
+
+	resp, err := m.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return ErrRecordNotFound
+	case http.StatusPreconditionFailed, http.StatusConflict:
+		return ErrEditConflict
+	default:
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+}
+
+// the authors endpoint sits next to the books endpoint, so http://localhost:4000/v1/books becomes http://localhost:4000/v1/authors
+func (m *ReadinglistModel) authorsEndpoint() string {
+	return strings.TrimSuffix(m.Endpoint, "/books") + "/authors"
+}
+
+// this method takes in an id and returns a pointer to that author and an error
+func (m *ReadinglistModel) GetAuthor(ctx context.Context, id int64) (*Author, error) {
+	resp, err := m.get(ctx, fmt.Sprintf("%s/%d", m.authorsEndpoint(), id))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrRecordNotFound
+	default:
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	var authorResp AuthorResponse
+
+	err = json.NewDecoder(resp.Body).Decode(&authorResp)
+	if err != nil {
+		return nil, err
+	}
+
+	return authorResp.Author, nil
+}
+
+// this method returns one page of the books an author worked on; the query values are used for paging
+func (m *ReadinglistModel) GetAuthorBooks(ctx context.Context, id int64, query url.Values) (*[]Book, *Metadata, error) {
+	endpoint := fmt.Sprintf("%s/%d/books", m.authorsEndpoint(), id)
+	if len(query) > 0 {
+		endpoint = fmt.Sprintf("%s?%s", endpoint, query.Encode())
+	}
+
+	resp, err := m.get(ctx, endpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	var booksResp BooksResponse
+
+	err = json.NewDecoder(resp.Body).Decode(&booksResp)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return booksResp.Books, &booksResp.Metadata, nil
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// HandlerTransport is an http.RoundTripper that hands each request straight to handler instead of sending it over the network
+// readinglist all uses it with the api's handler so the web app can call the api in the same process
+// the whole response is held in memory before it is returned, which is fine for the small JSON bodies the api sends
+func HandlerTransport(handler http.Handler) http.RoundTripper {
+	return handlerTransport{handler: handler}
+}
+
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (t handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	//a RoundTripper mustn't change the request it is given, so the handler gets a copy filled in the way a server fills in the requests it reads
+	r := req.Clone(req.Context())
+	r.RequestURI = req.URL.RequestURI()
+	r.RemoteAddr = "127.0.0.1:0" //a server always fills in the client address, so handlers are given one here too
+	if r.Body == nil {
+		r.Body = http.NoBody
+	}
+
+	buf := &responseBuffer{header: make(http.Header)}
+	t.handler.ServeHTTP(buf, r)
+
+	//a RoundTripper always closes the request body, even when the handler didn't read it
+	r.Body.Close()
+
+	//a handler that never writes anything still sends a 200
+	if buf.status == 0 {
+		buf.WriteHeader(http.StatusOK)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", buf.status, http.StatusText(buf.status)),
+		StatusCode:    buf.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        buf.sent,
+		Body:          io.NopCloser(&buf.body),
+		ContentLength: int64(buf.body.Len()),
+		Request:       req,
+	}, nil
+}
+
+// responseBuffer is the http.ResponseWriter a handler writes to in a HandlerTransport
+type responseBuffer struct {
+	header http.Header
+	sent   http.Header //a copy of header when WriteHeader was called; changes after that aren't sent, the same as with a real connection
+	status int
+	body   bytes.Buffer
+}
+
+func (b *responseBuffer) Header() http.Header {
+	return b.header
+}
+
+func (b *responseBuffer) WriteHeader(status int) {
+	if b.status != 0 {
+		return
+	}
+
+	b.status = status
+	b.sent = b.header.Clone()
+}
+
+func (b *responseBuffer) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.WriteHeader(http.StatusOK)
+	}
+
+	return b.body.Write(p)
+}
@@ -0,0 +1,202 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"readinglist/internal/requestid"
+)
+
+// received is what the stub api saw of a request
+type received struct {
+	method, uri, remoteAddr string
+	header                  http.Header
+	body                    string
+}
+
+// newStubModel is a ReadinglistModel whose calls go through HandlerTransport to respond, which stands in for the api
+// every request the stub gets is recorded in the returned slice
+func newStubModel(respond func(w http.ResponseWriter, r *http.Request)) (*ReadinglistModel, *[]received) {
+	var requests []received
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, received{method: r.Method, uri: r.RequestURI, remoteAddr: r.RemoteAddr, header: r.Header, body: string(body)})
+		respond(w, r)
+	})
+
+	return &ReadinglistModel{
+		Endpoint: "http://localhost:4000/v1/books",
+		Token:    "T0KEN",
+		Client:   &http.Client{Transport: HandlerTransport(handler)},
+	}, &requests
+}
+
+func TestHandlerTransportGet(t *testing.T) {
+	m, requests := newStubModel(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", `"3"`)
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, `{"book": {"id": 7, "title": "The Hobbit", "genres": ["Fantasy"], "status": "reading", "current_page": 12}}`)
+	})
+
+	ctx := requestid.NewContext(context.Background(), "0123456789abcdef0123456789abcdef")
+
+	book, err := m.Get(ctx, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Book{ID: 7, Title: "The Hobbit", Genres: []string{"Fantasy"}, Status: "reading", CurrentPage: 12, ETag: `"3"`}
+	if !reflect.DeepEqual(book, want) {
+		t.Errorf("book = %+v; want %+v", book, want)
+	}
+
+	if len(*requests) != 1 {
+		t.Fatalf("the api got %d requests; want 1", len(*requests))
+	}
+	got := (*requests)[0]
+
+	if got.method != http.MethodGet || got.uri != "/v1/books/7" {
+		t.Errorf("request = %s %s; want GET /v1/books/7", got.method, got.uri)
+	}
+	if auth := got.header.Get("Authorization"); auth != "Bearer T0KEN" {
+		t.Errorf("Authorization = %q; want the model's token", auth)
+	}
+	if id := got.header.Get(requestid.Header); id != "0123456789abcdef0123456789abcdef" {
+		t.Errorf("%s = %q; want the id from the context", requestid.Header, id)
+	}
+	//the api rate limits by address, so the in-process request needs one
+	if got.remoteAddr == "" {
+		t.Error("RemoteAddr is empty")
+	}
+}
+
+func TestHandlerTransportUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr error
+		wantVE  ValidationErrors
+	}{
+		{name: "saved", status: http.StatusOK, body: `{"book": {"id": 7, "title": "The Hobbit"}}`},
+		{name: "changed since it was read", status: http.StatusPreconditionFailed, body: `{"error": "the book has changed"}`, wantErr: ErrEditConflict},
+		{name: "rejected", status: http.StatusUnprocessableEntity, body: `{"error": {"pages": "must be provided"}}`, wantVE: ValidationErrors{"pages": "must be provided"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, requests := newStubModel(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("ETag", `"4"`)
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			})
+
+			book, err := m.Update(context.Background(), 7, `"3"`, BookInput{Title: "The Hobbit"})
+
+			switch {
+			case tt.wantVE != nil:
+				var ve ValidationErrors
+				if !errors.As(err, &ve) || !reflect.DeepEqual(ve, tt.wantVE) {
+					t.Errorf("err = %v; want %v", err, tt.wantVE)
+				}
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("err = %v; want %v", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Fatal(err)
+			default:
+				if book.Title != "The Hobbit" || book.ETag != `"4"` {
+					t.Errorf("book = %+v; want the saved book with the new ETag", book)
+				}
+			}
+
+			got := (*requests)[0]
+			if got.method != http.MethodPut || got.uri != "/v1/books/7" {
+				t.Errorf("request = %s %s; want PUT /v1/books/7", got.method, got.uri)
+			}
+			if ifMatch := got.header.Get("If-Match"); ifMatch != `"3"` {
+				t.Errorf("If-Match = %q; want the ETag passed to Update", ifMatch)
+			}
+			if contentType := got.header.Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("Content-Type = %q; want application/json", contentType)
+			}
+			//the lists are sent empty rather than null because the api counts null as a missing field
+			if want := `{"title":"The Hobbit","published":0,"pages":0,"genres":[],"rating":0,"status":"","current_page":0,"authors":[]}`; got.body != want {
+				t.Errorf("body = %s; want %s", got.body, want)
+			}
+		})
+	}
+}
+
+func TestHandlerTransportResponse(t *testing.T) {
+	transport := HandlerTransport(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, "hello")
+		//a header set after the body has started isn't sent, the same as over the network
+		w.Header().Set("X-Too-Late", "1")
+	}))
+
+	req, err := http.NewRequest(http.MethodGet, "http://localhost:4000/v1/healthcheck", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//a handler that writes without calling WriteHeader sends a 200
+	if resp.StatusCode != http.StatusOK || resp.Status != "200 OK" {
+		t.Errorf("status = %d %q; want 200", resp.StatusCode, resp.Status)
+	}
+	if string(body) != "hello" || resp.ContentLength != 5 {
+		t.Errorf("body = %q (length %d); want hello", body, resp.ContentLength)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "text/plain" {
+		t.Errorf("Content-Type = %q; want text/plain", got)
+	}
+	if got := resp.Header.Get("X-Too-Late"); got != "" {
+		t.Errorf("X-Too-Late = %q; want a header set after the body not to be sent", got)
+	}
+	if resp.Request != req {
+		t.Error("the response doesn't point at the request that was sent")
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	m, _ := newStubModel(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `{"error": "the requested resource could not be found"}`)
+	})
+
+	ctx := context.Background()
+
+	//a 404 from any of the calls for one record is ErrRecordNotFound, so the web app can show its own 404 page
+	if _, err := m.Get(ctx, 7); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("Get err = %v; want ErrRecordNotFound", err)
+	}
+	if _, err := m.GetAuthor(ctx, 7); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("GetAuthor err = %v; want ErrRecordNotFound", err)
+	}
+	if _, err := m.Update(ctx, 7, `"1"`, BookInput{}); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("Update err = %v; want ErrRecordNotFound", err)
+	}
+	if err := m.Delete(ctx, 7, `"1"`); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("Delete err = %v; want ErrRecordNotFound", err)
+	}
+}
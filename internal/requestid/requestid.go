@@ -0,0 +1,48 @@
+package requestid
+
+//this package gives every request an id that is passed from the web app to the api in the X-Request-ID header
+//the id is logged by both servers, so a failed page can be matched up with the api calls it made
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+)
+
+// Header is the name of the HTTP header the id is sent in
+const Header = "X-Request-ID"
+
+// contextKey is a private type so the key can't clash with ones set by other packages
+type contextKey struct{}
+
+// validRX limits the ids taken from clients to something that is safe to log and send back
+var validRX = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// New returns a random 32 character hex id
+func New() string {
+	b := make([]byte, 16)
+
+	//crypto/rand only fails when the operating system can't supply random bytes, and then nothing else will work either
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// Valid reports whether an id sent by a client can be used as it is
+func Valid(id string) bool {
+	return validRX.MatchString(id)
+}
+
+// NewContext returns a copy of ctx that carries the id
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the id in ctx, or an empty string if there isn't one
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
@@ -0,0 +1,63 @@
+package validator
+
+//this package collects validation errors for the fields of a request
+//each field gets at most one message so the client can show it next to the input that caused it
+
+import "regexp"
+
+// Validator holds a map of field names to error messages
+type Validator struct {
+	Errors map[string]string
+}
+
+// New returns a Validator with an empty errors map
+func New() *Validator {
+	return &Validator{Errors: make(map[string]string)}
+}
+
+// Valid returns true when no errors have been added
+func (v *Validator) Valid() bool {
+	return len(v.Errors) == 0
+}
+
+// AddError adds a message for a field as long as the field doesn't already have one
+// the first problem found with a field is usually the most useful one to show
+func (v *Validator) AddError(key, message string) {
+	if _, exists := v.Errors[key]; !exists {
+		v.Errors[key] = message
+	}
+}
+
+// Check adds the message for the field only if ok is false
+// this lets a validation rule be written as one line, for example v.Check(pages > 0, "pages", "must be greater than zero")
+func (v *Validator) Check(ok bool, key, message string) {
+	if !ok {
+		v.AddError(key, message)
+	}
+}
+
+// PermittedValue returns true if value is one of the permitted values
+func PermittedValue[T comparable](value T, permittedValues ...T) bool {
+	for i := range permittedValues {
+		if value == permittedValues[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches returns true if the string matches the regular expression
+func Matches(value string, rx *regexp.Regexp) bool {
+	return rx.MatchString(value)
+}
+
+// Unique returns true if no value appears in the slice more than once
+func Unique[T comparable](values []T) bool {
+	uniqueValues := make(map[T]bool)
+
+	for _, value := range values {
+		uniqueValues[value] = true
+	}
+
+	return len(values) == len(uniqueValues)
+}
@@ -0,0 +1,44 @@
+package validator
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestCheckKeepsTheFirstMessage(t *testing.T) {
+	v := New()
+
+	v.Check(true, "title", "must be provided")
+	if !v.Valid() {
+		t.Fatalf("errors = %v after a passing check; want none", v.Errors)
+	}
+
+	v.Check(false, "title", "must be provided")
+	v.Check(false, "title", "must not be more than 500 bytes long")
+	if v.Valid() || v.Errors["title"] != "must be provided" {
+		t.Errorf("errors = %v; want only the first message for title", v.Errors)
+	}
+}
+
+func TestHelpers(t *testing.T) {
+	tests := []struct {
+		name string
+		got  bool
+		want bool
+	}{
+		{"permitted", PermittedValue("reading", "want_to_read", "reading"), true},
+		{"not permitted", PermittedValue("skimmed", "want_to_read", "reading"), false},
+		{"nothing permitted", PermittedValue("reading"), false},
+		{"matches", Matches("alice@example.com", regexp.MustCompile(`^\S+@\S+$`)), true},
+		{"doesn't match", Matches("alice", regexp.MustCompile(`^\S+@\S+$`)), false},
+		{"unique", Unique([]string{"Fantasy", "Classic"}), true},
+		{"duplicates", Unique([]string{"Fantasy", "Fantasy"}), false},
+		{"empty is unique", Unique([]int{}), true},
+	}
+
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v; want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
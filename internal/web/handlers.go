@@ -0,0 +1,543 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"readinglist/internal/models"
+)
+
+// homePage is the data passed into the home page template
+type homePage struct {
+	Books    *[]models.Book
+	Metadata *models.Metadata
+	Title    string //the title filter, so it can be shown in the filter box again
+	Q        string //the search terms, so they can be shown in the search box again
+	PrevURL  string
+	NextURL  string
+}
+
+// authorPage is the data passed into the author page template
+type authorPage struct {
+	Author   *models.Author
+	Books    *[]models.Book
+	Metadata *models.Metadata
+	PrevURL  string
+	NextURL  string
+}
+
+// pageURL copies the current filters and swaps in a different page number so the paging links keep the filters
+func pageURL(query url.Values, page int) string {
+	q := url.Values{}
+	for key, values := range query {
+		q[key] = values
+	}
+	q.Set("page", strconv.Itoa(page))
+
+	return "/?" + q.Encode()
+}
+
+// these functions are all methods on the application type
+func (app *application) home(w http.ResponseWriter, r *http.Request) {
+	//only the filters the web service understands are passed along to it
+	query := url.Values{}
+	for _, key := range []string{"title", "genres", "page", "page_size", "sort"} {
+		if value := r.URL.Query().Get(key); value != "" {
+			query.Set(key, value)
+		}
+	}
+
+	//when the search box is used the books come from a full-text search instead of the filters
+	var books *[]models.Book
+	var metadata *models.Metadata
+	var err error
+
+	q := r.URL.Query().Get("q")
+	if q != "" {
+		books, metadata, err = app.readinglist.Search(r.Context(), q, query)
+		query.Set("q", q) //this keeps the search terms in the paging links
+	} else {
+		books, metadata, err = app.readinglist.GetAll(r.Context(), query) //populating variable books with one page of book records from the database and return them as a Go object
+	}
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	//the data for the home page is the books plus the links to the previous and next pages
+	data := homePage{
+		Books:    books,
+		Metadata: metadata,
+		Title:    query.Get("title"),
+		Q:        q,
+	}
+
+	if metadata.CurrentPage > metadata.FirstPage {
+		data.PrevURL = pageURL(query, metadata.CurrentPage-1)
+	}
+
+	if metadata.CurrentPage < metadata.LastPage {
+		data.NextURL = pageURL(query, metadata.CurrentPage+1)
+	}
+
+	//below is a variable that is a slice of strings that have the path to the templates we want to use on the home page
+	files := []string{
+		"./ui/html/base.html",
+		"./ui/html/partials/nav.html",
+		"./ui/html/pages/home.html",
+	}
+
+	//this parses the files at the string locations in the variable files
+	ts, err := template.ParseFiles(files...)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	//this executes the template base first and then pulls in other templates
+	err = ts.ExecuteTemplate(w, "base", data) //this takes in the io writer, the name of the first template, and the data for the page
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+}
+
+func (app *application) bookView(w http.ResponseWriter, r *http.Request) { //this returns a single book record
+	id, err := strconv.Atoi(r.URL.Query().Get("id")) //this gets the id from the URL and converts it from a string to an int
+	if err != nil || id < 1 {
+		http.NotFound(w, r)
+		return
+	}
+
+	book, err := app.readinglist.Get(r.Context(), int64(id)) //this get the specific book linked to the id int converted to the int64 type
+	if err != nil {
+		app.modelError(w, r, err)
+		return
+	}
+
+	//this pulls in the files with the templates we'll combine to create the page
+	files := []string{
+		"./ui/html/base.html",
+		"./ui/html/partials/nav.html",
+		"./ui/html/pages/view.html",
+	}
+
+	//used to convert comma-separated genres to a slice within the template
+	funcs := template.FuncMap{"join": strings.Join}
+
+	ts, err := template.New("showBook").Funcs(funcs).ParseFiles(files...)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = ts.ExecuteTemplate(w, "base", book)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// authorView shows an author and a page of the books they worked on
+func (app *application) authorView(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id")) //this gets the id from the URL and converts it from a string to an int
+	if err != nil || id < 1 {
+		http.NotFound(w, r)
+		return
+	}
+
+	author, err := app.readinglist.GetAuthor(r.Context(), int64(id))
+	if err != nil {
+		app.modelError(w, r, err)
+		return
+	}
+
+	//only the paging values are passed on to the web service
+	query := url.Values{}
+	if page := r.URL.Query().Get("page"); page != "" {
+		query.Set("page", page)
+	}
+
+	books, metadata, err := app.readinglist.GetAuthorBooks(r.Context(), int64(id), query)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := authorPage{
+		Author:   author,
+		Books:    books,
+		Metadata: metadata,
+	}
+
+	query.Set("id", strconv.Itoa(id)) //the paging links need to keep the author id
+
+	if metadata.CurrentPage > metadata.FirstPage {
+		query.Set("page", strconv.Itoa(metadata.CurrentPage-1))
+		data.PrevURL = "/author/view?" + query.Encode()
+	}
+
+	if metadata.CurrentPage < metadata.LastPage {
+		query.Set("page", strconv.Itoa(metadata.CurrentPage+1))
+		data.NextURL = "/author/view?" + query.Encode()
+	}
+
+	files := []string{
+		"./ui/html/base.html",
+		"./ui/html/partials/nav.html",
+		"./ui/html/pages/author.html",
+	}
+
+	ts, err := template.ParseFiles(files...)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = ts.ExecuteTemplate(w, "base", data)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// createPage is the data passed into the create page template
+type createPage struct {
+	CSRFToken string
+}
+
+// creating a book takes two routes: GET /book/create displays the form and POST /book/create adds the new book record
+func (app *application) bookCreateForm(w http.ResponseWriter, r *http.Request) {
+	files := []string{
+		"./ui/html/base.html",
+		"./ui/html/partials/nav.html",
+		"./ui/html/pages/create.html",
+	}
+
+	ts, err := template.ParseFiles(files...)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	//the only thing the form needs is the CSRF token
+	err = ts.ExecuteTemplate(w, "base", createPage{CSRFToken: csrfToken(r)})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+func (app *application) bookCreateProcess(w http.ResponseWriter, r *http.Request) {
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	//the value in the Get method needs to match the name attribute in the html form
+	title := r.PostForm.Get("title")
+
+	//published and pages needs to include error handling because they are being converted from one data type to another
+	published, err := strconv.Atoi(r.PostForm.Get("published"))
+	if err != nil {
+		app.clientError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	pages, err := strconv.Atoi(r.PostForm.Get("pages"))
+	if err != nil {
+		app.clientError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	//genres is being split apart at each comma and converting it into a slice of strings
+	genres := strings.Split(r.PostForm.Get("genres"), ",")
+
+	rating, err := strconv.ParseFloat(r.PostForm.Get("rating"), 32)
+	if err != nil {
+		app.clientError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	//below is an anonymous struct - those have to be instantiated right away
+	book := struct {
+		Title     string   `json:"title"`
+		Pages     int      `json:"pages"`
+		Published int      `json:"published"`
+		Genres    []string `json:"genres"`
+		Rating    float32  `json:"rating"`
+	}{ //this is a struct literal
+		Title:     title,
+		Pages:     pages,
+		Published: published,
+		Genres:    genres,
+		Rating:    float32(rating),
+	}
+
+	data, err := json.Marshal(book)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), "POST", app.readinglist.Endpoint, bytes.NewBuffer(data))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	req.Header.Set("Content-type", "application/json")
+
+	//the model sends the request so the bearer token goes with it
+	resp, err := app.readinglist.Do(req) //this is where the request is actually sent to the web service
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		app.serverError(w, r, fmt.Errorf("unexpected status from the web service: %s", resp.Status))
+		return
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther) //redirects to the homepage which will be updated with the new book as landing there sends a request for all books in the table
+}
+
+// bookForm is the data passed into the edit page template
+// the numbers are kept as the text that was typed so a form with a mistake in it can be shown again as it was
+type bookForm struct {
+	ID          int64
+	ETag        string //the version of the book the form was filled in from, sent back as a hidden field
+	Title       string
+	Published   string
+	Pages       string
+	Genres      string
+	Rating      string
+	Status      string
+	CurrentPage string
+	Errors      map[string]string //what is wrong with each field, from the form itself or from the web service
+	Conflict    bool              //the book was changed by someone else while the form was open
+	CSRFToken   string
+}
+
+// newBookForm fills the edit form in from a book
+func newBookForm(book *models.Book) bookForm {
+	return bookForm{
+		ID:          book.ID,
+		ETag:        book.ETag,
+		Title:       book.Title,
+		Published:   strconv.Itoa(book.Published),
+		Pages:       strconv.Itoa(book.Pages),
+		Genres:      strings.Join(book.Genres, ", "),
+		Rating:      strconv.FormatFloat(float64(book.Rating), 'f', -1, 32),
+		Status:      book.Status,
+		CurrentPage: strconv.Itoa(book.CurrentPage),
+		Errors:      map[string]string{},
+	}
+}
+
+// deletePage is the data passed into the delete confirmation page template
+type deletePage struct {
+	Book      *models.Book
+	Conflict  bool //the book was changed by someone else while the page was open
+	CSRFToken string
+}
+
+// bookEditForm shows the edit form filled in with the book as it is now
+func (app *application) bookEditForm(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || id < 1 {
+		http.NotFound(w, r)
+		return
+	}
+
+	book, err := app.readinglist.Get(r.Context(), int64(id))
+	if err != nil {
+		app.modelError(w, r, err)
+		return
+	}
+
+	form := newBookForm(book)
+	form.CSRFToken = csrfToken(r)
+
+	app.render(w, r, http.StatusOK, "edit.html", form)
+}
+
+// bookEditProcess saves the edit form
+// the ETag from when the form was opened goes with the change, so it isn't saved over a change someone else made in the meantime
+func (app *application) bookEditProcess(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || id < 1 {
+		http.NotFound(w, r)
+		return
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		app.clientError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	form := bookForm{
+		ID:          int64(id),
+		ETag:        r.PostForm.Get("etag"),
+		Title:       r.PostForm.Get("title"),
+		Published:   r.PostForm.Get("published"),
+		Pages:       r.PostForm.Get("pages"),
+		Genres:      r.PostForm.Get("genres"),
+		Rating:      r.PostForm.Get("rating"),
+		Status:      r.PostForm.Get("status"),
+		CurrentPage: r.PostForm.Get("current_page"),
+		Errors:      map[string]string{},
+		CSRFToken:   csrfToken(r),
+	}
+
+	//without the ETag the change would be saved over whatever the book is now, so a form without one is refused
+	if form.ETag == "" {
+		app.clientError(w, r, http.StatusBadRequest, errors.New("the form has no etag"))
+		return
+	}
+
+	input := models.BookInput{
+		Title:  form.Title,
+		Status: form.Status,
+	}
+
+	//the numbers that can't be read are shown next to their fields instead of rejecting the whole form
+	if input.Published, err = strconv.Atoi(form.Published); err != nil {
+		form.Errors["published"] = "must be a whole number"
+	}
+	if input.Pages, err = strconv.Atoi(form.Pages); err != nil {
+		form.Errors["pages"] = "must be a whole number"
+	}
+	if input.CurrentPage, err = strconv.Atoi(form.CurrentPage); err != nil {
+		form.Errors["current_page"] = "must be a whole number"
+	}
+
+	rating, err := strconv.ParseFloat(form.Rating, 32)
+	if err != nil {
+		form.Errors["rating"] = "must be a number"
+	}
+	input.Rating = float32(rating)
+
+	//the spaces after the commas are dropped so "Sci-Fi, Classic" is two genres and not " Classic"
+	for _, genre := range strings.Split(form.Genres, ",") {
+		if genre = strings.TrimSpace(genre); genre != "" {
+			input.Genres = append(input.Genres, genre)
+		}
+	}
+
+	if len(form.Errors) > 0 {
+		app.render(w, r, http.StatusUnprocessableEntity, "edit.html", form)
+		return
+	}
+
+	//the form doesn't change the authors, but the web service replaces the whole book, so they are sent back as they are
+	current, err := app.readinglist.Get(r.Context(), int64(id))
+	if err != nil {
+		app.modelError(w, r, err)
+		return
+	}
+
+	input.Authors = current.Authors
+
+	_, err = app.readinglist.Update(r.Context(), int64(id), form.ETag, input)
+
+	var validationErrors models.ValidationErrors
+
+	switch {
+	case err == nil:
+		http.Redirect(w, r, fmt.Sprintf("/book/view?id=%d", id), http.StatusSeeOther)
+
+	//the form is shown again with what was typed, and with the ETag of the book as it is now, so saving it again replaces the other change on purpose
+	case errors.Is(err, models.ErrEditConflict):
+		latest, err := app.readinglist.Get(r.Context(), int64(id))
+		if err != nil {
+			app.modelError(w, r, err)
+			return
+		}
+
+		form.ETag = latest.ETag
+		form.Conflict = true
+		app.render(w, r, http.StatusConflict, "edit.html", form)
+
+	case errors.As(err, &validationErrors):
+		form.Errors = validationErrors
+		app.render(w, r, http.StatusUnprocessableEntity, "edit.html", form)
+
+	default:
+		app.modelError(w, r, err)
+	}
+}
+
+// bookDeleteConfirm asks whether the book really should be deleted; nothing is deleted until the page's form is sent
+func (app *application) bookDeleteConfirm(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || id < 1 {
+		http.NotFound(w, r)
+		return
+	}
+
+	book, err := app.readinglist.Get(r.Context(), int64(id))
+	if err != nil {
+		app.modelError(w, r, err)
+		return
+	}
+
+	app.render(w, r, http.StatusOK, "delete.html", deletePage{Book: book, CSRFToken: csrfToken(r)})
+}
+
+// bookDeleteProcess deletes the book once it has been confirmed
+// like editing, the ETag from the confirmation page goes with it so a book that was changed in the meantime isn't deleted unseen
+func (app *application) bookDeleteProcess(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || id < 1 {
+		http.NotFound(w, r)
+		return
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		app.clientError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	//an empty ETag would delete the book whatever it is now, so a form without one is refused
+	etag := r.PostForm.Get("etag")
+	if etag == "" {
+		app.clientError(w, r, http.StatusBadRequest, errors.New("the form has no etag"))
+		return
+	}
+
+	err = app.readinglist.Delete(r.Context(), int64(id), etag)
+
+	switch {
+	case err == nil:
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+
+	//the confirmation is asked for again, this time showing the book as it is now
+	case errors.Is(err, models.ErrEditConflict):
+		book, err := app.readinglist.Get(r.Context(), int64(id))
+		if err != nil {
+			app.modelError(w, r, err)
+			return
+		}
+
+		app.render(w, r, http.StatusConflict, "delete.html", deletePage{Book: book, Conflict: true, CSRFToken: csrfToken(r)})
+
+	default:
+		app.modelError(w, r, err)
+	}
+}
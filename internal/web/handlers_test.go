@@ -0,0 +1,247 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"readinglist/internal/models"
+)
+
+func TestMain(m *testing.M) {
+	//the templates are read from ./ui/html, which is at the root of the module
+	if err := os.Chdir("../.."); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	os.Exit(m.Run())
+}
+
+// fakeAPI stands in for the api's books endpoint with a single book, id 1
+// the book's version is its ETag, and a version of 0 means it has been deleted
+type fakeAPI struct {
+	mu      sync.Mutex
+	version int
+	title   string
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.URL.Path != "/v1/books/1" || f.version == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `{"error": "the requested resource could not be found"}`)
+		return
+	}
+
+	etag := fmt.Sprintf(`"%d"`, f.version)
+	if r.Method != http.MethodGet && r.Header.Get("If-Match") != "" && r.Header.Get("If-Match") != etag {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		io.WriteString(w, `{"error": "the record has changed since it was read"}`)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		var input models.BookInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.title = input.Title
+		f.version++
+
+	case http.MethodDelete:
+		f.version = 0
+		io.WriteString(w, `{"message": "book successfully deleted"}`)
+		return
+	}
+
+	w.Header().Set("ETag", fmt.Sprintf(`"%d"`, f.version))
+	fmt.Fprintf(w, `{"book": {"id": 1, "title": %q, "published": 1937, "pages": 310, "genres": ["Fantasy"], "status": "reading", "current_page": 12, "authors": []}}`, f.title)
+}
+
+// newTestHandler is the web app with all of its middleware, calling a fakeAPI whose book is at version 1
+func newTestHandler(t *testing.T) (http.Handler, *fakeAPI) {
+	t.Helper()
+
+	api := &fakeAPI{version: 1, title: "The Hobbit"}
+
+	srv := NewServer(Config{
+		Endpoint:  "http://localhost:4000/v1/books",
+		Token:     "T0KEN",
+		Transport: models.HandlerTransport(api),
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	return srv.app.handler(), api
+}
+
+// getCSRFCookie opens the page at path and returns the CSRF cookie the web app gave the browser with it
+func getCSRFCookie(t *testing.T, handler http.Handler, path string) *http.Cookie {
+	t.Helper()
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("GET %s = %d; want 200", path, rr.Code)
+	}
+
+	for _, cookie := range rr.Result().Cookies() {
+		if cookie.Name == csrfField {
+			//the form on the page has to carry the same token as the cookie
+			if !strings.Contains(rr.Body.String(), `name="csrf_token" value="`+cookie.Value+`"`) {
+				t.Fatalf("GET %s: the form doesn't have the CSRF token from the cookie", path)
+			}
+			return cookie
+		}
+	}
+
+	t.Fatalf("GET %s didn't set a CSRF cookie", path)
+	return nil
+}
+
+// postForm sends form to path the way a browser would, with the cookie if there is one
+func postForm(handler http.Handler, path string, form url.Values, cookie *http.Cookie) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, r)
+	return rr
+}
+
+func TestMissingBookNotFound(t *testing.T) {
+	handler, _ := newTestHandler(t)
+
+	//a book the api doesn't have is a 404 page rather than a 500
+	for _, path := range []string{"/book/view?id=2", "/book/edit?id=2", "/book/delete?id=2"} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("GET %s = %d; want 404", path, rr.Code)
+		}
+	}
+
+	cookie := getCSRFCookie(t, handler, "/book/delete?id=1")
+	form := url.Values{csrfField: {cookie.Value}, "etag": {`"1"`}}
+	if rr := postForm(handler, "/book/delete?id=2", form, cookie); rr.Code != http.StatusNotFound {
+		t.Errorf("POST /book/delete?id=2 = %d; want 404", rr.Code)
+	}
+}
+
+func TestBookDeleteProcess(t *testing.T) {
+	tests := []struct {
+		name        string
+		noCookie    bool
+		token       string //the form's csrf_token; "cookie" means the cookie's value
+		etag        string
+		wantStatus  int
+		wantDeleted bool
+	}{
+		{name: "deleted", token: "cookie", etag: `"1"`, wantStatus: http.StatusSeeOther, wantDeleted: true},
+		{name: "no CSRF token", etag: `"1"`, wantStatus: http.StatusForbidden},
+		{name: "wrong CSRF token", token: "not-the-cookie", etag: `"1"`, wantStatus: http.StatusForbidden},
+		{name: "no CSRF cookie", noCookie: true, token: "cookie", etag: `"1"`, wantStatus: http.StatusForbidden},
+		{name: "no etag", token: "cookie", wantStatus: http.StatusBadRequest},
+		{name: "changed since the page was opened", token: "cookie", etag: `"0"`, wantStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, api := newTestHandler(t)
+			cookie := getCSRFCookie(t, handler, "/book/delete?id=1")
+
+			form := url.Values{}
+			if tt.token == "cookie" {
+				form.Set(csrfField, cookie.Value)
+			} else if tt.token != "" {
+				form.Set(csrfField, tt.token)
+			}
+			if tt.etag != "" {
+				form.Set("etag", tt.etag)
+			}
+			if tt.noCookie {
+				cookie = nil
+			}
+
+			rr := postForm(handler, "/book/delete?id=1", form, cookie)
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("status = %d; want %d", rr.Code, tt.wantStatus)
+			}
+
+			if deleted := api.version == 0; deleted != tt.wantDeleted {
+				t.Errorf("deleted = %v; want %v", deleted, tt.wantDeleted)
+			}
+		})
+	}
+}
+
+func TestBookEditProcess(t *testing.T) {
+	tests := []struct {
+		name       string
+		token      string //the form's csrf_token; "cookie" means the cookie's value
+		etag       string
+		wantStatus int
+		wantTitle  string
+	}{
+		{name: "saved", token: "cookie", etag: `"1"`, wantStatus: http.StatusSeeOther, wantTitle: "There and Back Again"},
+		{name: "no CSRF token", etag: `"1"`, wantStatus: http.StatusForbidden, wantTitle: "The Hobbit"},
+		{name: "no etag", token: "cookie", wantStatus: http.StatusBadRequest, wantTitle: "The Hobbit"},
+		{name: "changed since the form was opened", token: "cookie", etag: `"0"`, wantStatus: http.StatusConflict, wantTitle: "The Hobbit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, api := newTestHandler(t)
+			cookie := getCSRFCookie(t, handler, "/book/edit?id=1")
+
+			form := url.Values{
+				"title":        {"There and Back Again"},
+				"published":    {"1937"},
+				"pages":        {"310"},
+				"genres":       {"Fantasy"},
+				"rating":       {"4.5"},
+				"status":       {"reading"},
+				"current_page": {"12"},
+			}
+			if tt.token == "cookie" {
+				form.Set(csrfField, cookie.Value)
+			}
+			if tt.etag != "" {
+				form.Set("etag", tt.etag)
+			}
+
+			rr := postForm(handler, "/book/edit?id=1", form, cookie)
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("status = %d; want %d", rr.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusSeeOther && rr.Header().Get("Location") != "/book/view?id=1" {
+				t.Errorf("Location = %q; want the book's page", rr.Header().Get("Location"))
+			}
+
+			//the conflict page is the form again, with the token so it can be sent once more
+			if tt.wantStatus == http.StatusConflict && !strings.Contains(rr.Body.String(), `value="`+cookie.Value+`"`) {
+				t.Error("the conflict page's form has no CSRF token")
+			}
+
+			if api.title != tt.wantTitle {
+				t.Errorf("title = %q; want %q", api.title, tt.wantTitle)
+			}
+		})
+	}
+}
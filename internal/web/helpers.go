@@ -0,0 +1,99 @@
+package web
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"net/http"
+
+	"readinglist/internal/models"
+	"readinglist/internal/requestid"
+)
+
+// serverError logs the real error and sends a generic 500 so internal details don't leak into the page
+func (app *application) serverError(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Error("server error", "method", r.Method, "uri", r.URL.RequestURI(), "request_id", requestid.FromContext(r.Context()), "error", err)
+
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+// modelError answers an error from the models: a book or author the web service doesn't have is a 404, and anything else is a 500
+func (app *application) modelError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, models.ErrRecordNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+
+	app.serverError(w, r, err)
+}
+
+// clientError logs why the request was rejected at debug level and sends the status to the browser
+func (app *application) clientError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	app.logger.Debug("client error", "method", r.Method, "uri", r.URL.RequestURI(), "request_id", requestid.FromContext(r.Context()), "status", status, "error", err)
+
+	http.Error(w, http.StatusText(status), status)
+}
+
+// errorPage is the data passed into the error page template
+type errorPage struct {
+	Status     int
+	StatusText string
+	RequestID  string
+}
+
+// renderError sends the error page for status, with the request id so a user can quote it
+// the page is rendered into a buffer first so a broken template can still fall back to a plain text error
+func (app *application) renderError(w http.ResponseWriter, r *http.Request, status int) {
+	files := []string{
+		"./ui/html/base.html",
+		"./ui/html/partials/nav.html",
+		"./ui/html/pages/error.html",
+	}
+
+	data := errorPage{
+		Status:     status,
+		StatusText: http.StatusText(status),
+		RequestID:  requestid.FromContext(r.Context()),
+	}
+
+	var buf bytes.Buffer
+
+	ts, err := template.ParseFiles(files...)
+	if err == nil {
+		err = ts.ExecuteTemplate(&buf, "base", data)
+	}
+	if err != nil {
+		app.logger.Error("rendering error page", "method", r.Method, "uri", r.URL.RequestURI(), "request_id", data.RequestID, "error", err)
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
+
+// render sends page with data and status; it is for the pages that are sent back with an error status, such as a form with mistakes in it
+// like renderError the page is rendered into a buffer first so a broken template doesn't leave half a page behind the status
+func (app *application) render(w http.ResponseWriter, r *http.Request, status int, page string, data any) {
+	files := []string{
+		"./ui/html/base.html",
+		"./ui/html/partials/nav.html",
+		"./ui/html/pages/" + page,
+	}
+
+	var buf bytes.Buffer
+
+	ts, err := template.ParseFiles(files...)
+	if err == nil {
+		err = ts.ExecuteTemplate(&buf, "base", data)
+	}
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
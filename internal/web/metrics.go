@@ -0,0 +1,60 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"readinglist/internal/metrics"
+	"readinglist/internal/middleware"
+)
+
+//this file holds the metrics served at /metrics; they are the same request metrics as the api's
+//plus the time taken by the calls the models make to the api
+
+// appMetrics are the page request metrics and the upstream call metrics
+type appMetrics struct {
+	registry         *metrics.Registry
+	requests         *middleware.RequestMetrics
+	upstreamRequests *metrics.CounterVec
+	upstreamDuration *metrics.HistogramVec
+}
+
+func newAppMetrics() *appMetrics {
+	registry := metrics.NewRegistry()
+
+	return &appMetrics{
+		registry:         registry,
+		requests:         middleware.NewRequestMetrics(registry),
+		upstreamRequests: registry.NewCounterVec("upstream_requests_total", "Number of requests made to the readinglist web service, by method and status code.", "method", "code"),
+		upstreamDuration: registry.NewHistogramVec("upstream_request_duration_seconds", "How long requests to the readinglist web service took, by method.", metrics.DefaultBuckets, "method"),
+	}
+}
+
+// roundTripperFunc lets a function be used as an http.RoundTripper
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// instrument wraps the transport the models use so every call to the web service is counted and timed
+// the time is up to when the response headers arrive; reading the body is left to the model
+// a call that fails without a response is counted with the code "error"
+func (m *appMetrics) instrument(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		start := time.Now()
+
+		resp, err := next.RoundTrip(req)
+
+		code := "error"
+		if err == nil {
+			code = strconv.Itoa(resp.StatusCode)
+		}
+
+		m.upstreamRequests.Inc(req.Method, code)
+		m.upstreamDuration.Observe(time.Since(start).Seconds(), req.Method)
+
+		return resp, err
+	})
+}
@@ -0,0 +1,82 @@
+package web
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/http"
+
+	"readinglist/internal/middleware"
+	"readinglist/internal/requestid"
+)
+
+// recoverPanic turns a panic in a handler into the 500 error page; see middleware.RecoverPanic
+func (app *application) recoverPanic(next http.Handler) http.Handler {
+	return middleware.RecoverPanic(app.logger, func(w http.ResponseWriter, r *http.Request) {
+		app.renderError(w, r, http.StatusInternalServerError)
+	}, next)
+}
+
+// requestID gives every page request a new id and puts it in the request context
+// the models forward it to the api, so the page and the api calls it made are logged under the same id
+func (app *application) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := requestid.New()
+
+		w.Header().Set(requestid.Header, id)
+
+		r = r.WithContext(requestid.NewContext(r.Context(), id))
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// contextKey is a private type for the keys this package puts in a request context
+type contextKey string
+
+const csrfContextKey = contextKey("csrf")
+
+// csrfField is both the name of the cookie that holds a browser's CSRF token and the name of the hidden form field that sends it back
+const csrfField = "csrf_token"
+
+// csrf stops other sites from sending the forms on the user's behalf
+// the browser is given a random token in a cookie and every form has the same token in a hidden field
+// another site can make the browser send the cookie but can't read it to fill in the field, so a POST whose field doesn't match is refused
+func (app *application) csrf(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var token string
+		if cookie, err := r.Cookie(csrfField); err == nil {
+			token = cookie.Value
+		}
+
+		if r.Method == http.MethodPost {
+			sent := r.PostFormValue(csrfField)
+			if token == "" || subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+				app.clientError(w, r, http.StatusForbidden, errors.New("the CSRF token is missing or doesn't match the cookie"))
+				return
+			}
+		}
+
+		//a browser that hasn't got a token yet is given one with the page, ready for the forms on it
+		if token == "" {
+			b := make([]byte, 32)
+			if _, err := rand.Read(b); err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+			token = base64.RawURLEncoding.EncodeToString(b)
+
+			http.SetCookie(w, &http.Cookie{Name: csrfField, Value: token, Path: "/", HttpOnly: true, SameSite: http.SameSiteLaxMode})
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), csrfContextKey, token)))
+	})
+}
+
+// csrfToken is the token csrf put in the request context; the pages with a form put it in the hidden csrf_token field
+func csrfToken(r *http.Request) string {
+	token, _ := r.Context().Value(csrfContextKey).(string)
+	return token
+}
@@ -0,0 +1,44 @@
+package web
+
+import (
+	"net/http"
+
+	"readinglist/internal/middleware"
+)
+
+// each pattern starts with the method it answers; the mux sends a 405 with an Allow header for any other method
+func (app *application) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	//below adds the file server to the web application, which is needed to access the css
+	//when this handler receives a request it removes the leading slash from the url path
+	//then it searches the /ui/static directory for the corresponding file to send the director
+	fileServer := http.FileServer(http.Dir("./ui/static/"))
+	//for the above to work correctly, we have to strip off the leading slash to ensure one is sent to the correct place
+	//the StripPrefix removes the leading slash
+	mux.Handle("GET /static/", http.StripPrefix("/static", fileServer))
+
+	//these are the routes
+	mux.HandleFunc("GET /{$}", app.home) //{$} only matches the slash itself, so any other path is a 404 rather than the home page
+	mux.HandleFunc("GET /book/view", app.bookView)
+	mux.HandleFunc("GET /book/create", app.bookCreateForm)     //shows the form
+	mux.HandleFunc("POST /book/create", app.bookCreateProcess) //adds the book from the form
+	mux.HandleFunc("GET /book/edit", app.bookEditForm)         //shows the form filled in with the book
+	mux.HandleFunc("POST /book/edit", app.bookEditProcess)     //saves the changes from the form
+	mux.HandleFunc("GET /book/delete", app.bookDeleteConfirm)  //asks whether to delete the book
+	mux.HandleFunc("POST /book/delete", app.bookDeleteProcess) //deletes it once confirmed
+	mux.HandleFunc("GET /author/view", app.authorView)
+
+	mux.Handle("GET /metrics", app.metrics.registry.Handler()) //scraped by Prometheus
+
+	return mux
+}
+
+// handler wraps the routes in the middleware, the outermost first:
+// requestID so the id is in the log line, middleware.RecordMetrics and middleware.LogRequest so they see the final status,
+// recoverPanic so a panic still gets the error page, and csrf around the forms
+func (app *application) handler() http.Handler {
+	mux := app.routes()
+
+	return app.requestID(middleware.RecordMetrics(app.metrics.requests, mux, middleware.LogRequest(app.logger, app.recoverPanic(app.csrf(mux)))))
+}
@@ -0,0 +1,57 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+// serve runs the web server on cfg.Addr until ctx is cancelled, which is when the process gets SIGINT or SIGTERM, and then shuts it down gracefully
+// the pages being rendered when that happens are given up to cfg.ShutdownTimeout to finish
+func (app *application) serve(ctx context.Context, cfg Config) error {
+	addr, timeout := cfg.Addr, cfg.ShutdownTimeout
+
+	srv := &http.Server{
+		Addr:         addr,
+		Handler:      app.handler(),
+		ErrorLog:     slog.NewLogLogger(app.logger.Handler(), slog.LevelError),
+		IdleTimeout:  cfg.IdleTimeout,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+	}
+
+	//it has room for the result so the goroutine isn't left stuck when the server failed to start and nobody is waiting
+	shutdownError := make(chan error, 1)
+
+	go func() {
+		<-ctx.Done()
+
+		app.logger.Info("shutting down the server", "timeout", timeout.String())
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		shutdownError <- srv.Shutdown(ctx)
+	}()
+
+	app.logger.Info("starting server", "addr", addr, "endpoint", app.readinglist.Endpoint)
+
+	err := srv.ListenAndServe() //this starts the web application
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	if err := <-shutdownError; err != nil {
+		return err
+	}
+
+	app.logger.Info("requests finished; closing idle connections to the web service")
+
+	//the web app has no database; the only thing left open is the pool of connections to the api
+	app.readinglist.Client.CloseIdleConnections()
+
+	app.logger.Info("stopped server", "addr", addr)
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package web
+
+//this package is the readinglist web app; it shows the books it gets from the api as html pages
+//it is run by cmd/web and by the serve-web and all subcommands of the readinglist binary at the root of the module
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"readinglist/internal/models"
+)
+
+type application struct {
+	logger      *slog.Logger
+	metrics     *appMetrics
+	readinglist *models.ReadinglistModel
+}
+
+// Config is what the web app needs to run
+type Config struct {
+	Addr            string        //the address the server listens on, such as :80
+	Endpoint        string        //the books endpoint of the api, such as http://localhost:4000/v1/books
+	Token           string        //the bearer token sent to the api; the api only shows a user their own books
+	ShutdownTimeout time.Duration //how long to wait for requests to finish when shutting down
+	//the server timeouts, the same as the api's -read-timeout, -write-timeout and -idle-timeout; zero means no timeout, as in http.Server
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	//Transport is how the models reach the api; nil means over the network with http.DefaultTransport
+	//readinglist all sets it to models.HandlerTransport so the calls go straight to the api in the same process
+	Transport http.RoundTripper
+}
+
+// Serve reads the flags in args and runs the web app until ctx is cancelled; it is what cmd/web and readinglist serve-web do
+// name is the command shown in the usage message
+func Serve(ctx context.Context, name string, args []string) error {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+
+	var cfg Config
+	fs.StringVar(&cfg.Addr, "addr", ":80", "HTTP network address")                                                        //using a flag means we can change it with command line arguments
+	fs.StringVar(&cfg.Endpoint, "endpoint", "http://localhost:4000/v1/books", "Endpoint for the readinglist web service") //this defines the endpoint for the readinglist
+	//the web service only shows a user their own books, so the web app needs a token from POST /v1/tokens/authentication
+	fs.StringVar(&cfg.Token, "api-token", os.Getenv("READINGLIST_API_TOKEN"), "Bearer token for the readinglist web service")
+	logLevel := fs.String("log-level", "info", "Minimum log level (debug|info|warn|error)")
+	fs.DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", 30*time.Second, "How long to wait for requests to finish when shutting down")
+	//the defaults are the api's, so a page has as long to be served as the api call behind it
+	fs.DurationVar(&cfg.ReadTimeout, "read-timeout", 10*time.Second, "How long the server waits to read a whole request")
+	fs.DurationVar(&cfg.WriteTimeout, "write-timeout", 30*time.Second, "How long the server has to write a response")
+	fs.DurationVar(&cfg.IdleTimeout, "idle-timeout", time.Minute, "How long a keep-alive connection is kept open between requests")
+	fs.Parse(args) //the flags above only get their values from the command line once this is called
+
+	//every log line is a JSON object on stdout, the same as the api
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(*logLevel)); err != nil {
+		return fmt.Errorf("invalid -log-level: %w", err)
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level}))
+
+	return NewServer(cfg, logger).Run(ctx)
+}
+
+// Server is the web app ready to Run
+type Server struct {
+	app *application
+	cfg Config //Run uses the address and the timeouts
+}
+
+func NewServer(cfg Config, logger *slog.Logger) *Server {
+	transport := cfg.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	metrics := newAppMetrics()
+
+	app := &application{
+		logger:  logger,
+		metrics: metrics,
+		readinglist: &models.ReadinglistModel{ //this sets the endpoint to the one in the config
+			Endpoint: cfg.Endpoint,
+			Token:    cfg.Token,
+			Client:   &http.Client{Transport: metrics.instrument(transport)}, //the calls to the web service are timed for /metrics
+		},
+	}
+
+	return &Server{app: app, cfg: cfg}
+}
+
+// Run serves the web app until ctx is cancelled and then shuts it down gracefully
+func (s *Server) Run(ctx context.Context) error {
+	//serve only returns once the server has been shut down, or failed to start
+	if err := s.app.serve(ctx, s.cfg); err != nil {
+		return err
+	}
+
+	s.app.logger.Info("shutdown complete")
+
+	return nil
+}
@@ -1,24 +1,136 @@
 package main
 
+//readinglist is the whole application in one binary; each subcommand runs a part of it
+//serve-api, migrate, permissions and all take the api's settings from flags, a config file and the environment (see internal/api/config.go),
+//and serve-web takes the same flags as cmd/web
+
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"net/http"
-	"strconv"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"readinglist/internal/api"
+	"readinglist/internal/models"
+	"readinglist/internal/web"
 )
 
+const usage = `usage: readinglist <command> [flags]
+
+commands:
+  serve-api   run the JSON api
+  serve-web   run the web app against an api somewhere else
+  migrate     change the database schema: migrate [flags] up|down|status|goto N
+  permissions change what a user can do: permissions [flags] grant|revoke <email> <code>... or list <email>
+              for example, permissions revoke reader@example.com books:write makes a read-only member
+  all         run the api and the web app in one process
+
+run readinglist <command> -h to see the flags of a command
+`
+
 func main() {
-	r := http.NewServeMux()
-	r.HandleFunc("/v1/books/{id}", getBookHandler).Methods("GET")
-	http.ListenAndServe(":8080", r)
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	command, args := os.Args[1], os.Args[2:]
+
+	//the context is cancelled on SIGINT or SIGTERM, which shuts the servers down gracefully
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var err error
+
+	switch command {
+	case "serve-api":
+		err = api.Serve(ctx, "readinglist serve-api", args)
+
+	case "serve-web":
+		err = web.Serve(ctx, "readinglist serve-web", args)
+
+	case "migrate":
+		err = api.Migrate("readinglist migrate", args)
+
+	case "permissions":
+		err = api.Permissions("readinglist permissions", args)
+
+	case "all":
+		err = runAll(ctx, args)
+
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(os.Stdout, usage)
+
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
+	}
+
+	switch {
+	case err == nil:
+	case errors.Is(err, flag.ErrHelp):
+		//-h printed the flags, which isn't a failure
+	case errors.Is(err, api.ErrUsage):
+		//the flag package has already said what was wrong, along with the usage message
+		os.Exit(2)
+	default:
+		slog.New(slog.NewJSONHandler(os.Stdout, nil)).Error("readinglist "+command+" stopped with an error", "error", err)
+		os.Exit(1)
+	}
 }
 
-func getBookHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	idStr := vars["id"]
-	idInt, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		fmt.Println(err)
-		return
+// runAll runs the api and the web app in one process, so the whole application can be deployed as one container
+// the web app's calls to the api go straight to the api's handler instead of over the network
+// the api is still served on its own port as well, for other clients and for monitoring
+func runAll(ctx context.Context, args []string) error {
+	//the web app's own settings are added to the api's, so they can be set in the config file and the environment too
+	var webAddr, token string
+
+	apiServer, err := api.NewServer("readinglist all", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&webAddr, "web-addr", ":8080", "Web app HTTP network address")
+		fs.StringVar(&token, "api-token", "", "Bearer token the web app uses for the api")
+	})
+	if err != nil || apiServer == nil { //a nil server means -print-config was given
+		return err
+	}
+
+	webServer := web.NewServer(web.Config{
+		Addr: webAddr,
+		//only the path matters when the calls stay in the process, but this is where the api can be reached as well
+		Endpoint:        fmt.Sprintf("http://localhost:%d/v1/books", apiServer.Port()),
+		Token:           token,
+		ShutdownTimeout: apiServer.ShutdownTimeout(),
+		ReadTimeout:     apiServer.ReadTimeout(),
+		WriteTimeout:    apiServer.WriteTimeout(),
+		IdleTimeout:     apiServer.IdleTimeout(),
+		Transport:       models.HandlerTransport(apiServer.Handler()),
+	}, apiServer.Logger().With("server", "web"))
+
+	//a signal stops the web app first; the api is only stopped after that so the pages being rendered can still call it
+	webCtx, stopWeb := context.WithCancel(ctx)
+	defer stopWeb()
+
+	apiCtx, stopAPI := context.WithCancel(context.Background())
+	defer stopAPI()
+
+	webDone := make(chan error, 1)
+	apiDone := make(chan error, 1)
+
+	go func() { webDone <- webServer.Run(webCtx) }()
+	go func() { apiDone <- apiServer.Run(apiCtx) }()
+
+	//whichever server stops first, because of a signal or because it failed, the other one is stopped too
+	select {
+	case err := <-webDone:
+		stopAPI()
+		return errors.Join(err, <-apiDone)
+
+	case err := <-apiDone:
+		stopWeb()
+		return errors.Join(err, <-webDone)
 	}
-	fmt.Fprint(w, idInt)
 }